@@ -1,14 +1,66 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"os"
 	"user_balance/internal/app"
 )
 
+var version = "dev"
+
 func main() {
-	log.Println("Приложение запускается...")
+	command := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		log.Println("Приложение запускается...")
+
+		app.Run()
+
+		log.Println("Приложение успешно завершено.")
+	case "migrate":
+		runMigrate(args)
+	case "version":
+		fmt.Println(version)
+	case "help", "-h", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "Неизвестная команда: %s\n\n", command)
+		usage()
+		os.Exit(2)
+	}
+}
+
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Не указана команда migrate: up, down или status")
+		os.Exit(2)
+	}
+
+	command := args[0]
+	flags := flag.NewFlagSet("migrate "+command, flag.ExitOnError)
+	steps := flags.Int("steps", 1, "количество откатываемых миграций (для down)")
+	dryRun := flags.Bool("dry-run", false, "показать миграции без выполнения")
+	flags.Parse(args[1:])
+
+	if err := app.RunMigrate(command, *steps, *dryRun, os.Stdout); err != nil {
+		log.Fatalf("Ошибка выполнения migrate %s: %v", command, err)
+	}
+}
 
-	app.Run()
+func usage() {
+	fmt.Fprintln(os.Stderr, `Использование: user_balance <команда> [аргументы]
 
-	log.Println("Приложение успешно завершено.")
+Команды:
+  serve                                 запустить HTTP сервер (по умолчанию)
+  migrate up [--dry-run]                применить новые миграции
+  migrate down [--steps N] [--dry-run]  откатить последние N миграций
+  migrate status                        показать состояние миграций
+  version                               вывести версию`)
 }
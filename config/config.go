@@ -1,7 +1,10 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
@@ -9,10 +12,16 @@ import (
 
 type (
 	Config struct {
-		Server `yaml:"server"`
-		PG     `yaml:"postgres"`
-		Kafka  `yaml:"kafka"`
-		Cron   `yaml:"cron"`
+		Server      `yaml:"server"`
+		Admin       `yaml:"admin"`
+		PG          `yaml:"postgres"`
+		Kafka       `yaml:"kafka"`
+		Cron        `yaml:"cron"`
+		Reservation `yaml:"reservation"`
+		Reports     `yaml:"reports"`
+		Outbox      `yaml:"outbox"`
+		Events      `yaml:"events"`
+		Migrations  `yaml:"migrations"`
 	}
 
 	Server struct {
@@ -21,6 +30,10 @@ type (
 		Level string `env-required:"true" yaml:"level" env:"LOG_LEVEL"`
 	}
 
+	Admin struct {
+		Token string `yaml:"token" env:"ADMIN_TOKEN"`
+	}
+
 	PG struct {
 		DBHost     string `env-required:"true" yaml:"host" env:"DB_HOST"`
 		DBPort     string `env-required:"true" yaml:"port" env:"DB_PORT"`
@@ -30,18 +43,75 @@ type (
 	}
 
 	Kafka struct {
-		Brokers string `env-required:"true" yaml:"brokers" env:"KAFKA_BROKERS"`
-		Topic   string `env-required:"true" yaml:"topic" env:"KAFKA_TOPIC"`
+		Brokers      string        `yaml:"brokers" env:"KAFKA_BROKERS"`
+		Topic        string        `env-default:"monthly-report" yaml:"topic" env:"KAFKA_TOPIC"`
+		ClientID     string        `env-default:"user-balance" yaml:"client_id" env:"KAFKA_CLIENT_ID"`
+		Version      string        `yaml:"version" env:"KAFKA_VERSION"`
+		RequiredAcks string        `env-default:"all" yaml:"required_acks" env:"KAFKA_REQUIRED_ACKS"`
+		MaxRetries   int           `env-default:"5" yaml:"max_retries" env:"KAFKA_MAX_RETRIES"`
+		RetryBackoff time.Duration `env-default:"100ms" yaml:"retry_backoff" env:"KAFKA_RETRY_BACKOFF"`
+		Idempotent   bool          `env-default:"false" yaml:"idempotent" env:"KAFKA_IDEMPOTENT"`
+		Compression  string        `env-default:"none" yaml:"compression" env:"KAFKA_COMPRESSION"`
+		TLS          KafkaTLS      `yaml:"tls"`
+		SASL         KafkaSASL     `yaml:"sasl"`
+
+		CommandTopic  string `env-default:"balance-commands" yaml:"command_topic" env:"KAFKA_COMMAND_TOPIC"`
+		ReplyTopic    string `env-default:"balance-command-replies" yaml:"reply_topic" env:"KAFKA_REPLY_TOPIC"`
+		ConsumerGroup string `env-default:"user-balance" yaml:"consumer_group" env:"KAFKA_CONSUMER_GROUP"`
+	}
+
+	KafkaTLS struct {
+		Enabled            bool   `env-default:"false" yaml:"enabled" env:"KAFKA_TLS_ENABLED"`
+		CAFile             string `yaml:"ca_file" env:"KAFKA_TLS_CA_FILE"`
+		CertFile           string `yaml:"cert_file" env:"KAFKA_TLS_CERT_FILE"`
+		KeyFile            string `yaml:"key_file" env:"KAFKA_TLS_KEY_FILE"`
+		InsecureSkipVerify bool   `env-default:"false" yaml:"insecure_skip_verify" env:"KAFKA_TLS_INSECURE_SKIP_VERIFY"`
+	}
+
+	KafkaSASL struct {
+		Mechanism string `yaml:"mechanism" env:"KAFKA_SASL_MECHANISM"`
+		User      string `yaml:"user" env:"KAFKA_SASL_USER"`
+		Password  string `yaml:"password" env:"KAFKA_SASL_PASSWORD"`
 	}
 
 	Cron struct {
-		Schedule string `env-required:"true" yaml:"schedule" env:"CRON_SCHEDULE"`
+		Schedule                   string        `env-required:"true" yaml:"schedule" env:"CRON_SCHEDULE"`
+		RunsRetention              time.Duration `env-default:"720h" yaml:"runs_retention" env:"JOB_RUNS_RETENTION"`
+		RunsPruneSchedule          string        `env-default:"0 3 * * *" yaml:"runs_prune_schedule" env:"JOB_RUNS_PRUNE_SCHEDULE"`
+		IdempotencyCleanupSchedule string        `env-default:"0 * * * *" yaml:"idempotency_cleanup_schedule" env:"IDEMPOTENCY_CLEANUP_SCHEDULE"`
+	}
+
+	Reservation struct {
+		TTL            time.Duration `env-default:"72h" yaml:"ttl" env:"RESERVATION_TTL"`
+		ExpirySchedule string        `env-default:"*/5 * * * *" yaml:"expiry_schedule" env:"RESERVATION_EXPIRY_SCHEDULE"`
+	}
+
+	Reports struct {
+		Storage string `env-default:"local" yaml:"storage" env:"REPORTS_STORAGE"`
+		Dir     string `env-default:"./reports" yaml:"dir" env:"REPORTS_DIR"`
+	}
+
+	Migrations struct {
+		AutoApply bool `env-default:"true" yaml:"auto_apply" env:"MIGRATIONS_AUTO_APPLY"`
+	}
+
+	Events struct {
+		Sink     string `env-default:"kafka" yaml:"sink" env:"EVENTS_SINK"`
+		File     string `env-default:"./events/events.jsonl" yaml:"file" env:"EVENTS_FILE"`
+		Encoding string `env-default:"json" yaml:"encoding" env:"EVENTS_ENCODING"`
+	}
+
+	Outbox struct {
+		Topic     string        `env-default:"balance-events" yaml:"topic" env:"OUTBOX_TOPIC"`
+		Interval  time.Duration `env-default:"1s" yaml:"interval" env:"OUTBOX_INTERVAL"`
+		BatchSize int           `env-default:"100" yaml:"batch_size" env:"OUTBOX_BATCH_SIZE"`
+		Lease     time.Duration `env-default:"30s" yaml:"lease" env:"OUTBOX_LEASE"`
 	}
 )
 
 func NewConfig(dotenvPath string) (*Config, error) {
 	err := godotenv.Load(dotenvPath)
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return nil, fmt.Errorf("ошибка при загрузке .env файла: %w", err)
 	}
 
@@ -8,7 +8,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func NewRouter(services *service.Service, logger *logrus.Logger) *http.ServeMux {
+func NewRouter(services *service.Service, adminToken string, logger *logrus.Logger) *http.ServeMux {
 	mux := http.NewServeMux()
 
 	apiV1 := "/api/v1"
@@ -17,9 +17,13 @@ func NewRouter(services *service.Service, logger *logrus.Logger) *http.ServeMux
 		w.WriteHeader(http.StatusOK)
 	})
 
-	handler.NewAccountRoutes(mux, apiV1+"/accounts", services.Account, logger)
+	handler.NewAccountRoutes(mux, apiV1+"/accounts", services.Account, services.Idempotency, logger)
+	handler.NewOperationRoutes(mux, apiV1+"/accounts", services.Operation, logger)
+	handler.NewStatementRoutes(mux, apiV1+"/accounts", services.Statement, logger)
 	handler.NewProductRoutes(mux, apiV1+"/products", services.Product, logger)
-	handler.NewReservationRoutes(mux, apiV1+"/reservations", services.Reservation, logger)
+	handler.NewReportRoutes(mux, apiV1+"/reports", services.Report, logger)
+	handler.NewReservationRoutes(mux, apiV1+"/reservations", services.Reservation, services.Idempotency, logger)
+	handler.NewAdminRoutes(mux, apiV1+"/admin", services.Jobs, adminToken, logger)
 
 	return mux
 }
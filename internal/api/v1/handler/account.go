@@ -9,12 +9,12 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func NewAccountRoutes(mux *http.ServeMux, basePath string, accountService service.Account, logger *logrus.Logger) {
+func NewAccountRoutes(mux *http.ServeMux, basePath string, accountService service.Account, idempotencyService service.Idempotency, logger *logrus.Logger) {
 	mux.HandleFunc(basePath+"/create", createAccountHandler(accountService, logger))
 	mux.HandleFunc(basePath+"/get", getAccountHandler(accountService, logger))
-	mux.HandleFunc(basePath+"/deposit", depositAccountHandler(accountService, logger))
-	mux.HandleFunc(basePath+"/withdraw", withdrawAccountHandler(accountService, logger))
-	mux.HandleFunc(basePath+"/transfer", transferAccountHandler(accountService, logger))
+	mux.HandleFunc(basePath+"/deposit", idempotent(idempotencyService, logger, depositAccountHandler(accountService, logger)))
+	mux.HandleFunc(basePath+"/withdraw", idempotent(idempotencyService, logger, withdrawAccountHandler(accountService, logger)))
+	mux.HandleFunc(basePath+"/transfer", idempotent(idempotencyService, logger, transferAccountHandler(accountService, logger)))
 }
 
 func createAccountHandler(accountService service.Account, logger *logrus.Logger) http.HandlerFunc {
@@ -73,15 +73,21 @@ func getAccountHandler(accountService service.Account, logger *logrus.Logger) ht
 		}
 
 		type response struct {
-			Id      int `json:"id"`
-			Balance int `json:"balance"`
+			Id        int `json:"id"`
+			Balance   int `json:"balance"`
+			Available int `json:"available"`
+			Held      int `json:"held"`
+			Total     int `json:"total"`
 		}
 
-		logger.Infof("Аккаунт успешно получен: ID %d, Баланс %d", account.Id, account.Balance)
+		logger.Infof("Аккаунт успешно получен: ID %d, Доступно %d, Заморожено %d", account.Id, account.Available(), account.Held)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(response{
-			Id:      account.Id,
-			Balance: account.Balance,
+			Id:        account.Id,
+			Balance:   account.Balance,
+			Available: account.Available(),
+			Held:      account.Held,
+			Total:     account.Total(),
 		})
 	}
 }
@@ -105,7 +111,7 @@ func depositAccountHandler(accountService service.Account, logger *logrus.Logger
 		}
 
 		amount, err := strconv.Atoi(amountParam)
-		if err != nil {
+		if err != nil || amount <= 0 {
 			logger.Warnf("Запрос к %s не выполнен: недопустимый формат суммы", r.URL.Path)
 			http.Error(w, "Недопустимый формат суммы", http.StatusBadRequest)
 			return
@@ -150,7 +156,7 @@ func withdrawAccountHandler(accountService service.Account, logger *logrus.Logge
 		}
 
 		amount, err := strconv.Atoi(amountParam)
-		if err != nil {
+		if err != nil || amount <= 0 {
 			logger.Warnf("Запрос к %s не выполнен: недопустимый формат суммы", r.URL.Path)
 			http.Error(w, "Недопустимый формат суммы", http.StatusBadRequest)
 			return
@@ -203,7 +209,7 @@ func transferAccountHandler(accountService service.Account, logger *logrus.Logge
 		}
 
 		amount, err := strconv.Atoi(amountParam)
-		if err != nil {
+		if err != nil || amount <= 0 {
 			logger.Warnf("Запрос к %s не выполнен: недопустимый формат суммы", r.URL.Path)
 			http.Error(w, "Недопустимый формат суммы", http.StatusBadRequest)
 			return
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"user_balance/internal/entity"
+	"user_balance/internal/repository/repoerrs"
+	"user_balance/internal/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+const adminTokenPrefix = "Bearer "
+
+func NewAdminRoutes(mux *http.ServeMux, basePath string, jobs service.Jobs, token string, logger *logrus.Logger) {
+	if token == "" {
+		logger.Warn("ADMIN_TOKEN не задан, административные эндпоинты отключены")
+	}
+	mux.HandleFunc(basePath+"/jobs", adminOnly(token, logger, listJobsHandler(jobs, logger)))
+	mux.HandleFunc(basePath+"/jobs/{name}/runs", adminOnly(token, logger, listJobRunsHandler(jobs, logger)))
+	mux.HandleFunc(basePath+"/jobs/{name}/trigger", adminOnly(token, logger, triggerJobHandler(jobs, logger)))
+	mux.HandleFunc(basePath+"/reports/backfill", adminOnly(token, logger, backfillReportHandler(jobs, logger)))
+}
+
+func adminOnly(token string, logger *logrus.Logger, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			logger.Warnf("Запрос к %s не выполнен: административные эндпоинты отключены", r.URL.Path)
+			http.Error(w, "Административные эндпоинты отключены", http.StatusForbidden)
+			return
+		}
+
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, adminTokenPrefix) ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, adminTokenPrefix)), []byte(token)) != 1 {
+			logger.Warnf("Запрос к %s не выполнен: недействительный токен администратора", r.URL.Path)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Требуется токен администратора", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func listJobsHandler(jobs service.Jobs, logger *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			logger.Warnf("Запрос к %s не выполнен: метод не разрешён", r.URL.Path)
+			http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+			return
+		}
+
+		list, err := jobs.ListJobs(r.Context())
+		if err != nil {
+			logger.Errorf("Не удалось получить список задач: %v", err)
+			http.Error(w, "Не удалось получить список задач", http.StatusInternalServerError)
+			return
+		}
+
+		logger.Infof("Список задач успешно получен: %d шт.", len(list))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(list)
+	}
+}
+
+func listJobRunsHandler(jobs service.Jobs, logger *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			logger.Warnf("Запрос к %s не выполнен: метод не разрешён", r.URL.Path)
+			http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := r.PathValue("name")
+		limit, err := parseOptionalInt(r.URL.Query(), "limit")
+		if err != nil || (limit != nil && *limit < 1) {
+			logger.Warnf("Запрос к %s не выполнен: недопустимый лимит", r.URL.Path)
+			http.Error(w, "Недопустимый лимит", http.StatusBadRequest)
+			return
+		}
+		pageSize := 0
+		if limit != nil {
+			pageSize = *limit
+		}
+
+		runs, err := jobs.ListJobRuns(r.Context(), name, pageSize)
+		if err != nil {
+			if errors.Is(err, repoerrs.ErrJobNotFound) {
+				logger.Warnf("Запрос к %s не выполнен: задача %s не найдена", r.URL.Path, name)
+				http.Error(w, "Задача не найдена", http.StatusNotFound)
+				return
+			}
+			logger.Errorf("Не удалось получить историю задачи %s: %v", name, err)
+			http.Error(w, "Не удалось получить историю задачи", http.StatusInternalServerError)
+			return
+		}
+		if runs == nil {
+			runs = []entity.JobRun{}
+		}
+
+		logger.Infof("История задачи %s успешно получена: %d запусков", name, len(runs))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(runs)
+	}
+}
+
+func triggerJobHandler(jobs service.Jobs, logger *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			logger.Warnf("Запрос к %s не выполнен: метод не разрешён", r.URL.Path)
+			http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := r.PathValue("name")
+		run, err := jobs.TriggerJob(r.Context(), name)
+		if err != nil {
+			writeJobError(w, r, logger, name, err)
+			return
+		}
+
+		logger.Infof("Задача %s запущена вручную: запуск %d", name, run.Id)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(run)
+	}
+}
+
+func backfillReportHandler(jobs service.Jobs, logger *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			logger.Warnf("Запрос к %s не выполнен: метод не разрешён", r.URL.Path)
+			http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+			return
+		}
+
+		year, month, ok := parseReportPeriod(w, r, logger)
+		if !ok {
+			return
+		}
+
+		force, err := strconv.ParseBool(r.URL.Query().Get("force"))
+		if err != nil && r.URL.Query().Get("force") != "" {
+			logger.Warnf("Запрос к %s не выполнен: недопустимое значение force", r.URL.Path)
+			http.Error(w, "Недопустимое значение force", http.StatusBadRequest)
+			return
+		}
+
+		run, err := jobs.BackfillMonthlyReport(r.Context(), year, month, force)
+		if err != nil {
+			if errors.Is(err, repoerrs.ErrInvalidPeriod) {
+				logger.Warnf("Запрос к %s не выполнен: период %02d.%d ещё не завершён", r.URL.Path, month, year)
+				http.Error(w, "Отчёт можно перестроить только за завершённый месяц", http.StatusBadRequest)
+				return
+			}
+			writeJobError(w, r, logger, "отчёта о выручке", err)
+			return
+		}
+
+		logger.Infof("Перестроение отчёта о выручке за %02d.%d запущено: запуск %d", month, year, run.Id)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(run)
+	}
+}
+
+func writeJobError(w http.ResponseWriter, r *http.Request, logger *logrus.Logger, name string, err error) {
+	switch {
+	case errors.Is(err, repoerrs.ErrJobNotFound):
+		logger.Warnf("Запрос к %s не выполнен: задача %s не найдена", r.URL.Path, name)
+		http.Error(w, "Задача не найдена", http.StatusNotFound)
+	case errors.Is(err, repoerrs.ErrJobRunning), errors.Is(err, repoerrs.ErrJobAlreadyRun):
+		logger.Warnf("Запрос к %s не выполнен: %v", r.URL.Path, err)
+		http.Error(w, "Задача уже выполняется", http.StatusConflict)
+	default:
+		logger.Errorf("Не удалось запустить задачу %s: %v", name, err)
+		http.Error(w, "Не удалось запустить задачу", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"user_balance/internal/repository/repoerrs"
+	"user_balance/internal/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(statusCode int) {
+	rec.statusCode = statusCode
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func idempotent(idempotencyService service.Idempotency, logger *logrus.Logger, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			logger.Warnf("Запрос к %s не выполнен: не удалось прочитать тело запроса", r.URL.Path)
+			http.Error(w, "Не удалось прочитать тело запроса", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(r.Method + "\n" + r.URL.Path + "\n" + r.URL.Query().Encode() + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		record, err := idempotencyService.Begin(r.Context(), key, requestHash)
+		if err != nil {
+			switch {
+			case errors.Is(err, repoerrs.ErrIdempotencyConflict):
+				http.Error(w, "Ключ идемпотентности уже использован с другим запросом", http.StatusConflict)
+			case errors.Is(err, repoerrs.ErrIdempotencyInProgress):
+				http.Error(w, "Запрос с этим ключом идемпотентности ещё выполняется", http.StatusConflict)
+			default:
+				http.Error(w, "Не удалось проверить ключ идемпотентности", http.StatusInternalServerError)
+			}
+			logger.Warnf("Запрос к %s с ключом %s не выполнен: %v", r.URL.Path, key, err)
+			return
+		}
+
+		if record != nil {
+			logger.Infof("Запрос к %s с ключом %s: возвращён сохранённый ответ", r.URL.Path, key)
+			w.Header().Set("Idempotent-Replayed", "true")
+			if record.ContentType != "" {
+				w.Header().Set("Content-Type", record.ContentType)
+			}
+			w.WriteHeader(*record.StatusCode)
+			w.Write(record.Response)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next(rec, r)
+
+		ctx := context.WithoutCancel(r.Context())
+		if rec.statusCode >= http.StatusInternalServerError {
+			idempotencyService.Release(ctx, key)
+			return
+		}
+		contentType := rec.Header().Get("Content-Type")
+		if contentType == "" && rec.body.Len() > 0 {
+			contentType = http.DetectContentType(rec.body.Bytes())
+		}
+		idempotencyService.Complete(ctx, key, rec.statusCode, contentType, rec.body.Bytes())
+	}
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user_balance/internal/entity"
+	"user_balance/internal/repository/repoerrs"
+
+	"github.com/sirupsen/logrus"
+)
+
+type fakeIdempotency struct {
+	record *entity.IdempotencyRecord
+	err    error
+
+	hashes    []string
+	completed []int
+	body      string
+	ctype     string
+	released  int
+}
+
+func (f *fakeIdempotency) Begin(ctx context.Context, key, requestHash string) (*entity.IdempotencyRecord, error) {
+	f.hashes = append(f.hashes, requestHash)
+	return f.record, f.err
+}
+
+func (f *fakeIdempotency) Complete(ctx context.Context, key string, statusCode int, contentType string, response []byte) error {
+	f.completed = append(f.completed, statusCode)
+	f.ctype = contentType
+	f.body = string(response)
+	return nil
+}
+
+func (f *fakeIdempotency) Release(ctx context.Context, key string) error {
+	f.released++
+	return nil
+}
+
+func (f *fakeIdempotency) Cleanup(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestIdempotent(t *testing.T) {
+	replayStatus := http.StatusOK
+
+	tests := []struct {
+		name          string
+		key           string
+		record        *entity.IdempotencyRecord
+		beginErr      error
+		nextStatus    int
+		wantStatus    int
+		wantBody      string
+		wantNext      bool
+		wantBegin     bool
+		wantCompleted []int
+		wantReleased  int
+		wantReplayed  bool
+	}{
+		{
+			name:       "без ключа запрос выполняется напрямую",
+			nextStatus: http.StatusOK,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":1}`,
+			wantNext:   true,
+		},
+		{
+			name:          "новый ключ сохраняет ответ",
+			key:           "k1",
+			nextStatus:    http.StatusOK,
+			wantStatus:    http.StatusOK,
+			wantBody:      `{"id":1}`,
+			wantNext:      true,
+			wantBegin:     true,
+			wantCompleted: []int{http.StatusOK},
+		},
+		{
+			name:          "клиентская ошибка тоже сохраняется",
+			key:           "k2",
+			nextStatus:    http.StatusBadRequest,
+			wantStatus:    http.StatusBadRequest,
+			wantBody:      `{"id":1}`,
+			wantNext:      true,
+			wantBegin:     true,
+			wantCompleted: []int{http.StatusBadRequest},
+		},
+		{
+			name:         "ошибка сервера освобождает ключ",
+			key:          "k3",
+			nextStatus:   http.StatusInternalServerError,
+			wantStatus:   http.StatusInternalServerError,
+			wantBody:     `{"id":1}`,
+			wantNext:     true,
+			wantBegin:    true,
+			wantReleased: 1,
+		},
+		{
+			name: "повтор возвращает сохранённый ответ",
+			key:  "k4",
+			record: &entity.IdempotencyRecord{
+				StatusCode:  &replayStatus,
+				ContentType: "application/json",
+				Response:    []byte(`{"id":42}`),
+			},
+			wantStatus:   http.StatusOK,
+			wantBody:     `{"id":42}`,
+			wantBegin:    true,
+			wantReplayed: true,
+		},
+		{
+			name:       "ключ с другим запросом",
+			key:        "k5",
+			beginErr:   repoerrs.ErrIdempotencyConflict,
+			wantStatus: http.StatusConflict,
+			wantBegin:  true,
+		},
+		{
+			name:       "запрос с ключом ещё выполняется",
+			key:        "k6",
+			beginErr:   repoerrs.ErrIdempotencyInProgress,
+			wantStatus: http.StatusConflict,
+			wantBegin:  true,
+		},
+		{
+			name:       "ошибка хранилища ключей",
+			key:        "k7",
+			beginErr:   errors.New("нет соединения"),
+			wantStatus: http.StatusInternalServerError,
+			wantBegin:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeIdempotency{record: tt.record, err: tt.beginErr}
+			called := false
+			next := func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.nextStatus)
+				io.WriteString(w, `{"id":1}`)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/accounts/deposit?id=1&amount=100", nil)
+			if tt.key != "" {
+				req.Header.Set(idempotencyKeyHeader, tt.key)
+			}
+			rec := httptest.NewRecorder()
+			idempotent(fake, testLogger(), next)(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("статус %d, ожидался %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("тело %q, ожидалось %q", rec.Body.String(), tt.wantBody)
+			}
+			if called != tt.wantNext {
+				t.Errorf("обработчик вызван: %v, ожидалось %v", called, tt.wantNext)
+			}
+			if got := len(fake.hashes) > 0; got != tt.wantBegin {
+				t.Errorf("ключ проверен: %v, ожидалось %v", got, tt.wantBegin)
+			}
+			if len(fake.completed) != len(tt.wantCompleted) || (len(tt.wantCompleted) > 0 && fake.completed[0] != tt.wantCompleted[0]) {
+				t.Errorf("сохранены ответы %v, ожидались %v", fake.completed, tt.wantCompleted)
+			}
+			if len(tt.wantCompleted) > 0 && (fake.body != `{"id":1}` || fake.ctype != "application/json") {
+				t.Errorf("сохранён ответ %q (%s)", fake.body, fake.ctype)
+			}
+			if fake.released != tt.wantReleased {
+				t.Errorf("ключ освобождён %d раз, ожидалось %d", fake.released, tt.wantReleased)
+			}
+			if got := rec.Header().Get("Idempotent-Replayed") == "true"; got != tt.wantReplayed {
+				t.Errorf("признак повтора %v, ожидался %v", got, tt.wantReplayed)
+			}
+		})
+	}
+}
+
+func TestIdempotentRequestHash(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    string
+		body      string
+		wantEqual bool
+	}{
+		{name: "тот же запрос", target: "/api/accounts/deposit?id=1&amount=100", wantEqual: true},
+		{name: "другая сумма", target: "/api/accounts/deposit?id=1&amount=200"},
+		{name: "другой путь", target: "/api/accounts/withdraw?id=1&amount=100"},
+		{name: "другое тело", target: "/api/accounts/deposit?id=1&amount=100", body: "x"},
+	}
+
+	hash := func(target, body string) string {
+		fake := &fakeIdempotency{}
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set(idempotencyKeyHeader, "k")
+		idempotent(fake, testLogger(), func(w http.ResponseWriter, r *http.Request) {})(httptest.NewRecorder(), req)
+		return fake.hashes[0]
+	}
+
+	base := hash("/api/accounts/deposit?id=1&amount=100", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hash(tt.target, tt.body) == base; got != tt.wantEqual {
+				t.Errorf("хеши совпадают: %v, ожидалось %v", got, tt.wantEqual)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"user_balance/internal/entity"
+	"user_balance/internal/repository/repoerrs"
+	"user_balance/internal/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+func NewOperationRoutes(mux *http.ServeMux, basePath string, operationService service.Operation, logger *logrus.Logger) {
+	mux.HandleFunc(basePath+"/{id}/operations", getAccountOperationsHandler(operationService, logger))
+}
+
+func getAccountOperationsHandler(operationService service.Operation, logger *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			logger.Warnf("Запрос к %s не выполнен: метод не разрешён", r.URL.Path)
+			http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			logger.Warnf("Запрос к %s не выполнен: недопустимый формат ID аккаунта", r.URL.Path)
+			http.Error(w, "Недопустимый формат ID аккаунта", http.StatusBadRequest)
+			return
+		}
+
+		filter, err := parseOperationFilter(r.URL.Query())
+		if err != nil {
+			logger.Warnf("Запрос к %s не выполнен: %v", r.URL.Path, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.AccountId = id
+
+		page, err := operationService.GetAccountOperations(r.Context(), filter, r.URL.Query().Get("cursor"))
+		if err != nil {
+			if errors.Is(err, repoerrs.ErrInvalidCursor) {
+				logger.Warnf("Запрос к %s не выполнен: %v", r.URL.Path, err)
+				http.Error(w, "Некорректный курсор пагинации", http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, sql.ErrNoRows) || errors.Is(err, repoerrs.ErrDataDeleted) {
+				logger.Warnf("Запрос к %s не выполнен: аккаунт с ID %d не найден", r.URL.Path, id)
+				http.Error(w, "Аккаунт не найден", http.StatusNotFound)
+				return
+			}
+			logger.Errorf("Не удалось получить операции аккаунта с ID %d: %v", id, err)
+			http.Error(w, "Не удалось получить операции аккаунта", http.StatusInternalServerError)
+			return
+		}
+
+		logger.Infof("Операции аккаунта с ID %d успешно получены: %d шт.", id, len(page.Operations))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(page)
+	}
+}
+
+func parseOperationFilter(query url.Values) (entity.OperationFilter, error) {
+	filter := entity.OperationFilter{
+		OperationType: query.Get("type"),
+		SortBy:        query.Get("sort"),
+		Desc:          query.Get("order") != "asc",
+	}
+
+	if filter.SortBy != "" && filter.SortBy != entity.OperationSortByDate && filter.SortBy != entity.OperationSortByAmount {
+		return filter, errors.New("недопустимое поле сортировки")
+	}
+	if order := query.Get("order"); order != "" && order != "asc" && order != "desc" {
+		return filter, errors.New("недопустимое направление сортировки")
+	}
+
+	var err error
+	if filter.ProductId, err = parseOptionalInt(query, "product_id"); err != nil {
+		return filter, errors.New("недопустимый формат ID продукта")
+	}
+	if filter.MinAmount, err = parseOptionalInt(query, "min_amount"); err != nil {
+		return filter, errors.New("недопустимый формат минимальной суммы")
+	}
+	if filter.MaxAmount, err = parseOptionalInt(query, "max_amount"); err != nil {
+		return filter, errors.New("недопустимый формат максимальной суммы")
+	}
+	if filter.From, err = parseOptionalTime(query, "from"); err != nil {
+		return filter, errors.New("недопустимый формат даты начала периода")
+	}
+	if filter.To, err = parseOptionalTime(query, "to"); err != nil {
+		return filter, errors.New("недопустимый формат даты конца периода")
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit <= 0 {
+			return filter, errors.New("недопустимый размер страницы")
+		}
+	}
+
+	return filter, nil
+}
+
+func parseOptionalInt(query url.Values, name string) (*int, error) {
+	param := query.Get(name)
+	if param == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.Atoi(param)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+func parseOptionalTime(query url.Values, name string) (*time.Time, error) {
+	param := query.Get(name)
+	if param == "" {
+		return nil, nil
+	}
+
+	value, err := time.Parse(time.RFC3339, param)
+	if err != nil {
+		value, err = time.Parse(time.DateOnly, param)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &value, nil
+}
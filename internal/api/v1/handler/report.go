@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"user_balance/internal/entity"
+	"user_balance/internal/service"
+	"user_balance/internal/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+func NewReportRoutes(mux *http.ServeMux, basePath string, reportService service.Report, logger *logrus.Logger) {
+	mux.HandleFunc(basePath+"/revenue", getRevenueReportHandler(reportService, logger))
+	mux.HandleFunc(basePath, listReportFilesHandler(basePath, reportService, logger))
+	mux.HandleFunc(basePath+"/{id}/download", downloadReportFileHandler(reportService, logger))
+}
+
+func getRevenueReportHandler(reportService service.Report, logger *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			logger.Warnf("Запрос к %s не выполнен: метод не разрешён", r.URL.Path)
+			http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+			return
+		}
+
+		year, month, ok := parseReportPeriod(w, r, logger)
+		if !ok {
+			return
+		}
+
+		report, err := reportService.GetRevenueReport(r.Context(), year, month)
+		if err != nil {
+			logger.Errorf("Не удалось сформировать отчёт о выручке за %02d.%d: %v", month, year, err)
+			http.Error(w, "Не удалось сформировать отчёт", http.StatusInternalServerError)
+			return
+		}
+
+		logger.Infof("Отчёт о выручке за %02d.%d успешно сформирован", month, year)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+func listReportFilesHandler(basePath string, reportService service.Report, logger *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			logger.Warnf("Запрос к %s не выполнен: метод не разрешён", r.URL.Path)
+			http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+			return
+		}
+
+		files, err := reportService.ListReportFiles(r.Context())
+		if err != nil {
+			logger.Errorf("Не удалось получить список файлов отчётов: %v", err)
+			http.Error(w, "Не удалось получить список отчётов", http.StatusInternalServerError)
+			return
+		}
+
+		type item struct {
+			entity.ReportFile
+			DownloadURL string `json:"download_url,omitempty"`
+		}
+
+		items := make([]item, 0, len(files))
+		for _, file := range files {
+			it := item{ReportFile: file}
+			if file.Status == entity.ReportCompleted {
+				it.DownloadURL = fmt.Sprintf("%s/%d/download", basePath, file.Id)
+			}
+			items = append(items, it)
+		}
+
+		logger.Infof("Список файлов отчётов успешно получен: %d шт.", len(items))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(items)
+	}
+}
+
+func downloadReportFileHandler(reportService service.Report, logger *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			logger.Warnf("Запрос к %s не выполнен: метод не разрешён", r.URL.Path)
+			http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			logger.Warnf("Запрос к %s не выполнен: недопустимый формат ID отчёта", r.URL.Path)
+			http.Error(w, "Недопустимый формат ID отчёта", http.StatusBadRequest)
+			return
+		}
+
+		file, content, err := reportService.OpenReportFile(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) || errors.Is(err, storage.ErrNotFound) {
+				logger.Warnf("Запрос к %s не выполнен: %v", r.URL.Path, err)
+				http.Error(w, "Файл отчёта не найден", http.StatusNotFound)
+				return
+			}
+			logger.Errorf("Не удалось открыть файл отчёта с ID %d: %v", id, err)
+			http.Error(w, "Не удалось открыть файл отчёта", http.StatusInternalServerError)
+			return
+		}
+		defer content.Close()
+
+		filename := fmt.Sprintf("revenue_%d-%02d.csv", file.Year, file.Month)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		if file.Checksum != nil {
+			w.Header().Set("X-Checksum-SHA256", *file.Checksum)
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, content); err != nil {
+			logger.Errorf("Ошибка при выгрузке файла отчёта с ID %d: %v", id, err)
+			return
+		}
+		logger.Infof("Файл отчёта с ID %d успешно выгружен", id)
+	}
+}
+
+func parseReportPeriod(w http.ResponseWriter, r *http.Request, logger *logrus.Logger) (int, time.Month, bool) {
+	year, err := strconv.Atoi(r.URL.Query().Get("year"))
+	if err != nil || year < 1 {
+		logger.Warnf("Запрос к %s не выполнен: недопустимый год", r.URL.Path)
+		http.Error(w, "Недопустимый год", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	month, err := strconv.Atoi(r.URL.Query().Get("month"))
+	if err != nil || month < 1 || month > 12 {
+		logger.Warnf("Запрос к %s не выполнен: недопустимый месяц", r.URL.Path)
+		http.Error(w, "Недопустимый месяц", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	return year, time.Month(month), true
+}
@@ -2,19 +2,25 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 	"user_balance/internal/entity"
+	"user_balance/internal/repository/repoerrs"
 	"user_balance/internal/service"
 
 	"github.com/sirupsen/logrus"
 )
 
-func NewReservationRoutes(mux *http.ServeMux, basePath string, reservationService service.Reservation, logger *logrus.Logger) {
-	mux.HandleFunc(basePath+"/create", createReservationHandler(reservationService))
+func NewReservationRoutes(mux *http.ServeMux, basePath string, reservationService service.Reservation, idempotencyService service.Idempotency, logger *logrus.Logger) {
+	mux.HandleFunc(basePath+"/create", idempotent(idempotencyService, logger, createReservationHandler(reservationService)))
 	mux.HandleFunc(basePath+"/get", getReservationHandler(reservationService))
-	mux.HandleFunc(basePath+"/refund", refundReservationHandler(reservationService))
+	mux.HandleFunc(basePath+"/refund", idempotent(idempotencyService, logger, refundReservationHandler(reservationService)))
+	mux.HandleFunc(basePath+"/confirm", idempotent(idempotencyService, logger, confirmReservationHandler(reservationService)))
+	mux.HandleFunc(basePath+"/orders/create", idempotent(idempotencyService, logger, createOrderReservationHandler(reservationService)))
+	mux.HandleFunc(basePath+"/orders/confirm", idempotent(idempotencyService, logger, confirmOrderHandler(reservationService)))
+	mux.HandleFunc(basePath+"/orders/refund", idempotent(idempotencyService, logger, refundOrderHandler(reservationService)))
 }
 
 func createReservationHandler(reservationService service.Reservation) http.HandlerFunc {
@@ -62,8 +68,23 @@ func createReservationHandler(reservationService service.Reservation) http.Handl
 			Amount:    amount,
 		}
 
+		if orderIDParam := r.URL.Query().Get("order_id"); orderIDParam != "" {
+			orderID, err := strconv.Atoi(orderIDParam)
+			if err != nil {
+				http.Error(w, "Неверный формат ID заказа", http.StatusBadRequest)
+				log.Printf("Ошибка: неверный формат ID заказа: %v\n", err)
+				return
+			}
+			reservation.OrderId = &orderID
+		}
+
 		reservationID, err := reservationService.CreateReservation(r.Context(), reservation)
 		if err != nil {
+			if errors.Is(err, repoerrs.ErrOrderReservationExists) {
+				http.Error(w, "Резервация по заказу уже создана с другой суммой", http.StatusConflict)
+				log.Printf("Ошибка при создании резервации: %v\n", err)
+				return
+			}
 			http.Error(w, "Не удалось создать резервацию", http.StatusInternalServerError)
 			log.Printf("Ошибка при создании резервации: %v\n", err)
 			return
@@ -84,6 +105,11 @@ func getReservationHandler(reservationService service.Reservation) http.HandlerF
 		}
 
 		idParam := r.URL.Query().Get("id")
+		if idParam == "" && r.URL.Query().Get("order_id") != "" {
+			getOrderReservations(w, r, reservationService)
+			return
+		}
+
 		if idParam == "" {
 			http.Error(w, "Отсутствует или некорректный ID резервации", http.StatusBadRequest)
 			log.Println("Ошибка: отсутствует или некорректный ID резервации")
@@ -110,6 +136,30 @@ func getReservationHandler(reservationService service.Reservation) http.HandlerF
 	}
 }
 
+func getOrderReservations(w http.ResponseWriter, r *http.Request, reservationService service.Reservation) {
+	accountID, orderID, ok := parseOrderParams(w, r)
+	if !ok {
+		return
+	}
+
+	reservations, err := reservationService.GetReservationsByOrder(r.Context(), accountID, orderID)
+	if err != nil {
+		http.Error(w, "Не удалось получить резервации по заказу", http.StatusInternalServerError)
+		log.Printf("Ошибка при получении резерваций по заказу с ID %d: %v\n", orderID, err)
+		return
+	}
+
+	if len(reservations) == 0 {
+		http.Error(w, "Резервации по заказу не найдены", http.StatusNotFound)
+		log.Printf("Резервации по заказу с ID %d не найдены\n", orderID)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reservations)
+	log.Printf("Резервации по заказу с ID %d успешно получены\n", orderID)
+}
+
 func refundReservationHandler(reservationService service.Reservation) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -132,8 +182,27 @@ func refundReservationHandler(reservationService service.Reservation) http.Handl
 			return
 		}
 
-		err = reservationService.RefundReservation(r.Context(), id)
+		amount, ok := parseOptionalAmount(w, r)
+		if !ok {
+			return
+		}
+
+		if amount != nil {
+			err = reservationService.PartialRefundReservation(r.Context(), id, *amount)
+		} else {
+			err = reservationService.RefundReservation(r.Context(), id)
+		}
 		if err != nil {
+			if errors.Is(err, repoerrs.ErrInvalidAmount) {
+				http.Error(w, "Некорректная сумма возврата", http.StatusBadRequest)
+				log.Printf("Ошибка при возврате резервации с ID %d: %v\n", id, err)
+				return
+			}
+			if isReservationConflict(err) {
+				http.Error(w, "Резервация уже закрыта", http.StatusConflict)
+				log.Printf("Ошибка при возврате резервации с ID %d: %v\n", id, err)
+				return
+			}
 			http.Error(w, "Не удалось вернуть резервацию", http.StatusInternalServerError)
 			log.Printf("Ошибка при возврате резервации с ID %d: %v\n", id, err)
 			return
@@ -144,3 +213,203 @@ func refundReservationHandler(reservationService service.Reservation) http.Handl
 		log.Printf("Резервация с ID %d успешно возвращена\n", id)
 	}
 }
+
+func confirmReservationHandler(reservationService service.Reservation) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+			log.Println("Ошибка: попытка использования недопустимого метода для подтверждения резервации")
+			return
+		}
+
+		idParam := r.URL.Query().Get("id")
+		if idParam == "" {
+			http.Error(w, "Отсутствует или некорректный ID резервации", http.StatusBadRequest)
+			log.Println("Ошибка: отсутствует или некорректный ID резервации")
+			return
+		}
+
+		id, err := strconv.Atoi(idParam)
+		if err != nil {
+			http.Error(w, "Неверный формат ID резервации", http.StatusBadRequest)
+			log.Printf("Ошибка: неверный формат ID резервации: %v\n", err)
+			return
+		}
+
+		amount, ok := parseOptionalAmount(w, r)
+		if !ok {
+			return
+		}
+
+		if amount != nil {
+			err = reservationService.CaptureReservation(r.Context(), id, *amount)
+		} else {
+			err = reservationService.ConfirmReservation(r.Context(), id)
+		}
+		if err != nil {
+			if errors.Is(err, repoerrs.ErrInvalidAmount) {
+				http.Error(w, "Некорректная сумма списания", http.StatusBadRequest)
+				log.Printf("Ошибка при подтверждении резервации с ID %d: %v\n", id, err)
+				return
+			}
+			if isReservationConflict(err) {
+				http.Error(w, "Резервация уже закрыта", http.StatusConflict)
+				log.Printf("Ошибка при подтверждении резервации с ID %d: %v\n", id, err)
+				return
+			}
+			http.Error(w, "Не удалось подтвердить резервацию", http.StatusInternalServerError)
+			log.Printf("Ошибка при подтверждении резервации с ID %d: %v\n", id, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode("Резервация успешно подтверждена")
+		log.Printf("Резервация с ID %d успешно подтверждена\n", id)
+	}
+}
+
+func createOrderReservationHandler(reservationService service.Reservation) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+			log.Println("Ошибка: попытка использования недопустимого метода для создания резервации по заказу")
+			return
+		}
+
+		var order entity.OrderReservation
+		if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+			http.Error(w, "Некорректное тело запроса", http.StatusBadRequest)
+			log.Printf("Ошибка: некорректное тело запроса: %v\n", err)
+			return
+		}
+
+		ids, err := reservationService.CreateOrderReservation(r.Context(), order)
+		if err != nil {
+			switch {
+			case errors.Is(err, repoerrs.ErrInvalidOrder), errors.Is(err, repoerrs.ErrInvalidAmount):
+				http.Error(w, "Некорректный состав заказа", http.StatusBadRequest)
+			case errors.Is(err, repoerrs.ErrOrderReservationExists):
+				http.Error(w, "Резервация по заказу уже создана с другим составом", http.StatusConflict)
+			default:
+				http.Error(w, "Не удалось создать резервацию по заказу", http.StatusInternalServerError)
+			}
+			log.Printf("Ошибка при создании резервации по заказу: %v\n", err)
+			return
+		}
+
+		type response struct {
+			OrderId        int   `json:"order_id"`
+			ReservationIds []int `json:"reservation_ids"`
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response{
+			OrderId:        order.OrderId,
+			ReservationIds: ids,
+		})
+		log.Printf("Резервация по заказу с ID %d успешно создана\n", order.OrderId)
+	}
+}
+
+func confirmOrderHandler(reservationService service.Reservation) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+			log.Println("Ошибка: попытка использования недопустимого метода для подтверждения заказа")
+			return
+		}
+
+		accountID, orderID, ok := parseOrderParams(w, r)
+		if !ok {
+			return
+		}
+
+		err := reservationService.ConfirmOrder(r.Context(), accountID, orderID)
+		if err != nil {
+			if isReservationConflict(err) {
+				http.Error(w, "Нет открытых позиций по заказу", http.StatusConflict)
+				log.Printf("Ошибка при подтверждении заказа с ID %d: %v\n", orderID, err)
+				return
+			}
+			http.Error(w, "Не удалось подтвердить заказ", http.StatusInternalServerError)
+			log.Printf("Ошибка при подтверждении заказа с ID %d: %v\n", orderID, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode("Заказ успешно подтверждён")
+		log.Printf("Заказ с ID %d успешно подтверждён\n", orderID)
+	}
+}
+
+func refundOrderHandler(reservationService service.Reservation) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+			log.Println("Ошибка: попытка использования недопустимого метода для возврата заказа")
+			return
+		}
+
+		accountID, orderID, ok := parseOrderParams(w, r)
+		if !ok {
+			return
+		}
+
+		err := reservationService.RefundOrder(r.Context(), accountID, orderID)
+		if err != nil {
+			if isReservationConflict(err) {
+				http.Error(w, "Нет открытых позиций по заказу", http.StatusConflict)
+				log.Printf("Ошибка при возврате заказа с ID %d: %v\n", orderID, err)
+				return
+			}
+			http.Error(w, "Не удалось вернуть заказ", http.StatusInternalServerError)
+			log.Printf("Ошибка при возврате заказа с ID %d: %v\n", orderID, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode("Заказ успешно возвращён")
+		log.Printf("Заказ с ID %d успешно возвращён\n", orderID)
+	}
+}
+
+func parseOrderParams(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	accountID, err := strconv.Atoi(r.URL.Query().Get("account_id"))
+	if err != nil {
+		http.Error(w, "Неверный формат ID аккаунта", http.StatusBadRequest)
+		log.Printf("Ошибка: неверный формат ID аккаунта: %v\n", err)
+		return 0, 0, false
+	}
+
+	orderID, err := strconv.Atoi(r.URL.Query().Get("order_id"))
+	if err != nil {
+		http.Error(w, "Неверный формат ID заказа", http.StatusBadRequest)
+		log.Printf("Ошибка: неверный формат ID заказа: %v\n", err)
+		return 0, 0, false
+	}
+
+	return accountID, orderID, true
+}
+
+func parseOptionalAmount(w http.ResponseWriter, r *http.Request) (*int, bool) {
+	amountParam := r.URL.Query().Get("amount")
+	if amountParam == "" {
+		return nil, true
+	}
+
+	amount, err := strconv.Atoi(amountParam)
+	if err != nil {
+		http.Error(w, "Неверный формат суммы", http.StatusBadRequest)
+		log.Printf("Ошибка: неверный формат суммы: %v\n", err)
+		return nil, false
+	}
+
+	return &amount, true
+}
+
+func isReservationConflict(err error) bool {
+	return errors.Is(err, repoerrs.ErrReservationConfirmed) ||
+		errors.Is(err, repoerrs.ErrReservationRefunded) ||
+		errors.Is(err, repoerrs.ErrReservationExpired) ||
+		errors.Is(err, repoerrs.ErrIllegalStatusTransition)
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"user_balance/internal/entity"
+	"user_balance/internal/repository/repoerrs"
+	"user_balance/internal/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	statementFlushEvery   = 100
+	statementWriteTimeout = 30 * time.Second
+)
+
+var statementCSVHeader = []string{"type", "date", "operation_id", "operation_type", "product_id", "amount", "balance"}
+
+func NewStatementRoutes(mux *http.ServeMux, basePath string, statementService service.Statement, logger *logrus.Logger) {
+	mux.HandleFunc(basePath+"/{id}/statement", getStatementHandler(statementService, logger))
+}
+
+func getStatementHandler(statementService service.Statement, logger *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			logger.Warnf("Запрос к %s не выполнен: метод не разрешён", r.URL.Path)
+			http.Error(w, "Метод не разрешён", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			logger.Warnf("Запрос к %s не выполнен: недопустимый формат ID аккаунта", r.URL.Path)
+			http.Error(w, "Недопустимый формат ID аккаунта", http.StatusBadRequest)
+			return
+		}
+
+		from, err := parseOptionalTime(r.URL.Query(), "from")
+		if err != nil || from == nil {
+			logger.Warnf("Запрос к %s не выполнен: отсутствует или недопустима дата начала периода", r.URL.Path)
+			http.Error(w, "Отсутствует или недопустима дата начала периода", http.StatusBadRequest)
+			return
+		}
+
+		to, err := parseOptionalTime(r.URL.Query(), "to")
+		if err != nil || to == nil || !to.After(*from) {
+			logger.Warnf("Запрос к %s не выполнен: отсутствует или недопустима дата конца периода", r.URL.Path)
+			http.Error(w, "Отсутствует или недопустима дата конца периода", http.StatusBadRequest)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "csv"
+		}
+
+		filename := fmt.Sprintf("statement_%d_%s_%s.%s", id, from.Format(time.DateOnly), to.Format(time.DateOnly), format)
+
+		out := &countingWriter{w: w}
+		var write func(entity.StatementEntry) error
+		var flush func() error
+		var contentType string
+		switch format {
+		case "csv":
+			writer := csv.NewWriter(out)
+			writer.Write(statementCSVHeader)
+			write = func(entry entity.StatementEntry) error {
+				return writer.Write(statementCSVRecord(entry))
+			}
+			flush = func() error {
+				writer.Flush()
+				return writer.Error()
+			}
+			contentType = "text/csv; charset=utf-8"
+		case "jsonl":
+			buffered := bufio.NewWriter(out)
+			encoder := json.NewEncoder(buffered)
+			write = func(entry entity.StatementEntry) error {
+				return encoder.Encode(entry)
+			}
+			flush = buffered.Flush
+			contentType = "application/x-ndjson"
+		default:
+			logger.Warnf("Запрос к %s не выполнен: недопустимый формат выписки %s", r.URL.Path, format)
+			http.Error(w, "Недопустимый формат выписки", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+		controller := http.NewResponseController(w)
+		extendDeadline := func() {
+			err := controller.SetWriteDeadline(time.Now().Add(statementWriteTimeout))
+			if err != nil && !errors.Is(err, http.ErrNotSupported) {
+				logger.Warnf("Не удалось продлить срок записи выписки по аккаунту с ID %d: %v", id, err)
+			}
+		}
+		extendDeadline()
+
+		written := 0
+		err = statementService.GenerateStatement(r.Context(), id, *from, *to, func(entry entity.StatementEntry) error {
+			if err := write(entry); err != nil {
+				return err
+			}
+			written++
+			if written%statementFlushEvery == 0 {
+				if err := flush(); err != nil {
+					return err
+				}
+				if err := controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+					return err
+				}
+				extendDeadline()
+			}
+			return nil
+		})
+		if err != nil {
+			if out.n > 0 {
+				logger.Errorf("Формирование выписки по аккаунту с ID %d прервано после %d байт: %v", id, out.n, err)
+				return
+			}
+			w.Header().Del("Content-Disposition")
+			if errors.Is(err, sql.ErrNoRows) || errors.Is(err, repoerrs.ErrDataDeleted) {
+				logger.Warnf("Запрос к %s не выполнен: аккаунт с ID %d не найден", r.URL.Path, id)
+				http.Error(w, "Аккаунт не найден", http.StatusNotFound)
+				return
+			}
+			logger.Errorf("Не удалось сформировать выписку по аккаунту с ID %d: %v", id, err)
+			http.Error(w, "Не удалось сформировать выписку", http.StatusInternalServerError)
+			return
+		}
+
+		if err := flush(); err != nil {
+			logger.Errorf("Не удалось записать выписку по аккаунту с ID %d: %v", id, err)
+			return
+		}
+		logger.Infof("Выписка по аккаунту с ID %d успешно выгружена: %d строк", id, written)
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
+
+func statementCSVRecord(entry entity.StatementEntry) []string {
+	record := []string{entry.Type, entry.Date.Format(time.RFC3339), "", "", "", "", strconv.Itoa(entry.Balance)}
+	if entry.Type == entity.StatementOperation {
+		record[2] = strconv.Itoa(entry.OperationId)
+		record[3] = entry.OperationType
+		if entry.ProductId != nil {
+			record[4] = strconv.Itoa(*entry.ProductId)
+		}
+		record[5] = strconv.Itoa(entry.Amount)
+	}
+	return record
+}
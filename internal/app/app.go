@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -15,66 +16,107 @@ import (
 	"user_balance/internal/service"
 
 	_ "github.com/lib/pq"
+	"github.com/sirupsen/logrus"
 )
 
 func Run() {
-	logger := SetLogrus("info")
-	logger.Info("Загрузка конфигурации...")
-
-	cfg, err := config.NewConfig(".env")
-	if err != nil {
-		logger.Fatalf("Ошибка загрузки конфигурации: %v", err)
-	}
+	cfg, logger := loadConfig()
 
-	logger = SetLogrus(cfg.Level)
-	logger.Info("Конфигурация успешно загружена.")
-
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName,
-	)
-	logger.Info("Подключение к базе данных...")
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		logger.Fatalf("Ошибка подключения к базе данных: %v", err)
-	}
+	db := openDatabase(cfg, logger)
 	defer func() {
 		if err := db.Close(); err != nil {
 			logger.Errorf("Ошибка при закрытии подключения к базе данных: %v", err)
 		}
 	}()
-	logger.Info("Подключение к базе данных успешно установлено.")
 
-	logger.Info("Применение миграций...")
-	if err := ApplyMigrations(db, "./migration"); err != nil {
-		logger.Fatalf("Ошибка применения миграций: %v", err)
+	if cfg.Migrations.AutoApply {
+		logger.Info("Применение миграций...")
+		if err := ApplyMigrations(db); err != nil {
+			logger.Fatalf("Ошибка применения миграций: %v", err)
+		}
+		logger.Info("Миграции успешно применены.")
+	} else {
+		logger.Info("Автоматическое применение миграций отключено.")
 	}
-	logger.Info("Миграции успешно применены.")
 
 	logger.Info("Инициализация компонентов приложения...")
+	reportStorage, err := NewReportStorage(cfg.Reports)
+	if err != nil {
+		logger.Fatalf("Ошибка инициализации хранилища отчётов: %v", err)
+	}
 	repository := repository.NewRepository(db)
-	service := service.NewService(repository, logger)
-	router := api.NewRouter(service, logger)
+	service := service.NewService(repository, reportStorage, logger)
 	logger.Info("Компоненты приложения успешно инициализированы.")
 
-	logger.Info("Инициализация Kafka producer...")
-	kafkaProducer, err := NewKafkaProducer(cfg.Kafka.Brokers, logger)
+	logger.Infof("Инициализация приёмника событий (%s)...", cfg.Events.Sink)
+	events, err := NewEventPublisher(cfg.Events, cfg.Kafka)
 	if err != nil {
-		logger.Fatalf("Ошибка инициализации Kafka producer: %v", err)
+		logger.Fatalf("Ошибка инициализации приёмника событий: %v", err)
+	}
+	defer events.Close()
+	logger.Info("Приёмник событий успешно инициализирован.")
+
+	logger.Info("Запуск outbox relay...")
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	relay := NewOutboxRelay(repository, events, cfg.Outbox.Topic, cfg.Outbox.Interval, cfg.Outbox.BatchSize, cfg.Outbox.Lease, logger)
+	relayDone := make(chan struct{})
+	go func() {
+		relay.Run(relayCtx)
+		close(relayDone)
+	}()
+	defer func() {
+		stopRelay()
+		<-relayDone
+	}()
+	logger.Info("Outbox relay успешно запущен.")
+
+	if len(KafkaBrokers(cfg.Kafka)) > 0 {
+		logger.Info("Инициализация Kafka consumer...")
+		commandConsumer, err := NewCommandConsumer(cfg.Kafka, service, events, logger)
+		if err != nil {
+			logger.Fatalf("Ошибка инициализации Kafka consumer: %v", err)
+		}
+		consumerCtx, stopConsumer := context.WithCancel(context.Background())
+		consumerDone := make(chan struct{})
+		go func() {
+			commandConsumer.Run(consumerCtx)
+			close(consumerDone)
+		}()
+		defer func() {
+			stopConsumer()
+			<-consumerDone
+			commandConsumer.Close()
+		}()
+		logger.Info("Kafka consumer успешно инициализирован.")
+	} else {
+		logger.Warn("Брокеры Kafka не заданы, Kafka consumer не запущен.")
 	}
-	defer kafkaProducer.Close()
-	logger.Info("Kafka producer успешно инициализирован.")
 
 	logger.Info("Инициализация Cron scheduler...")
-	scheduler := NewScheduler(logger)
-	job := scheduler.GenerateMonthlyReportJob(repository, kafkaProducer, cfg.Kafka.Topic)
-	if err := scheduler.AddJob(cfg.Cron.Schedule, job); err != nil {
+	scheduler := NewScheduler(repository, logger)
+	reportJob := scheduler.GenerateMonthlyReportJob(service.Report, events, cfg.Kafka.Topic)
+	if err := scheduler.Register(MonthlyReportJobName, cfg.Cron.Schedule, reportJob); err != nil {
 		logger.Fatalf("Ошибка добавления Cron задачи: %v", err)
 	}
+	expireJob := scheduler.ExpireReservationsJob(service.Reservation, cfg.Reservation.TTL)
+	if err := scheduler.Register(ExpireReservationsJobName, cfg.Reservation.ExpirySchedule, expireJob); err != nil {
+		logger.Fatalf("Ошибка добавления Cron задачи истечения резерваций: %v", err)
+	}
+	pruneJob := scheduler.PruneJobRunsJob(cfg.Cron.RunsRetention)
+	if err := scheduler.Register(PruneJobRunsJobName, cfg.Cron.RunsPruneSchedule, pruneJob); err != nil {
+		logger.Fatalf("Ошибка добавления Cron задачи очистки истории запусков: %v", err)
+	}
+	cleanupJob := scheduler.CleanupIdempotencyKeysJob(service.Idempotency)
+	if err := scheduler.Register(CleanupIdempotencyJobName, cfg.Cron.IdempotencyCleanupSchedule, cleanupJob); err != nil {
+		logger.Fatalf("Ошибка добавления Cron задачи очистки ключей идемпотентности: %v", err)
+	}
+	service.Jobs = scheduler
 	scheduler.Start()
 	defer scheduler.Stop()
 	logger.Info("Cron scheduler успешно инициализирован.")
 
+	router := api.NewRouter(service, cfg.Admin.Token, logger)
+
 	logger.Info("Запуск HTTP сервера...")
 	httpServer := httpserver.New(
 		router,
@@ -100,3 +142,31 @@ func Run() {
 	}
 	logger.Info("Приложение завершило работу.")
 }
+
+func loadConfig() (*config.Config, *logrus.Logger) {
+	logger := SetLogrus("info")
+	logger.Info("Загрузка конфигурации...")
+
+	cfg, err := config.NewConfig(".env")
+	if err != nil {
+		logger.Fatalf("Ошибка загрузки конфигурации: %v", err)
+	}
+
+	logger = SetLogrus(cfg.Level)
+	logger.Info("Конфигурация успешно загружена.")
+	return cfg, logger
+}
+
+func openDatabase(cfg *config.Config, logger *logrus.Logger) *sql.DB {
+	dsn := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName,
+	)
+	logger.Info("Подключение к базе данных...")
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		logger.Fatalf("Ошибка подключения к базе данных: %v", err)
+	}
+	logger.Info("Подключение к базе данных успешно установлено.")
+	return db
+}
@@ -0,0 +1,416 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"user_balance/config"
+	"user_balance/internal/entity"
+	"user_balance/internal/publisher"
+	"user_balance/internal/repository/repoerrs"
+	"user_balance/internal/service"
+	"user_balance/pkg/event"
+
+	"github.com/IBM/sarama"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	commandIdempotencyPrefix = "kafka:"
+	commandRetryBackoff      = 100 * time.Millisecond
+	commandRetryMaxBackoff   = 30 * time.Second
+	commandMaxAttempts       = 5
+)
+
+var (
+	errUnknownCommand = errors.New("неизвестный тип команды")
+	errReplyFailed    = errors.New("не удалось отправить результат команды")
+)
+
+type commandFailure struct {
+	result entity.CommandResult
+	err    error
+}
+
+func (f *commandFailure) Error() string {
+	return f.err.Error()
+}
+
+func (f *commandFailure) Unwrap() error {
+	return f.err
+}
+
+type CommandConsumer struct {
+	group        sarama.ConsumerGroup
+	service      *service.Service
+	events       publisher.EventPublisher
+	topic        string
+	replyTopic   string
+	retryBackoff time.Duration
+	logger       *logrus.Logger
+}
+
+func NewCommandConsumer(cfg config.Kafka, service *service.Service, events publisher.EventPublisher, logger *logrus.Logger) (*CommandConsumer, error) {
+	saramaConfig, err := NewSaramaConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	saramaConfig.Consumer.Return.Errors = true
+	saramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	group, err := sarama.NewConsumerGroup(KafkaBrokers(cfg), cfg.ConsumerGroup, saramaConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommandConsumer{
+		group:        group,
+		service:      service,
+		events:       events,
+		topic:        cfg.CommandTopic,
+		replyTopic:   cfg.ReplyTopic,
+		retryBackoff: commandRetryBackoff,
+		logger:       logger,
+	}, nil
+}
+
+func (c *CommandConsumer) Run(ctx context.Context) {
+	go func() {
+		for err := range c.group.Errors() {
+			c.logger.Errorf("Ошибка Kafka consumer group: %v", err)
+		}
+	}()
+
+	for {
+		if err := c.group.Consume(ctx, []string{c.topic}, c); err != nil {
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				return
+			}
+			c.logger.Errorf("Ошибка чтения команд из топика %s: %v", c.topic, err)
+		}
+		if ctx.Err() != nil {
+			c.logger.Info("Kafka consumer остановлен.")
+			return
+		}
+	}
+}
+
+func (c *CommandConsumer) Close() {
+	if err := c.group.Close(); err != nil {
+		c.logger.Errorf("Ошибка закрытия Kafka consumer: %v", err)
+	} else {
+		c.logger.Info("Kafka consumer закрыт")
+	}
+}
+
+func (c *CommandConsumer) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (c *CommandConsumer) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (c *CommandConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case <-session.Context().Done():
+			return nil
+		case message, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if err := c.handleWithRetry(session.Context(), message); err != nil {
+				return nil
+			}
+			session.MarkMessage(message, "")
+		}
+	}
+}
+
+func (c *CommandConsumer) handleWithRetry(ctx context.Context, message *sarama.ConsumerMessage) error {
+	backoff := c.retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := c.handleMessage(ctx, message)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= commandMaxAttempts && !isTransientError(err) {
+			c.logger.Errorf("Команда из %s/%d со смещением %d не обработана за %d попыток, отправляется отказ: %v",
+				message.Topic, message.Partition, message.Offset, attempt, err)
+			if replyErr := c.replyResult(ctx, failedCommandResult(message, err)); replyErr == nil {
+				return nil
+			}
+		}
+
+		c.logger.Warnf("Команда из %s/%d со смещением %d не обработана, повтор через %s: %v",
+			message.Topic, message.Partition, message.Offset, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, commandRetryMaxBackoff)
+	}
+}
+
+func (c *CommandConsumer) handleMessage(ctx context.Context, message *sarama.ConsumerMessage) error {
+	key := string(message.Key)
+	if key == "" {
+		result, cause := c.execute(ctx, message.Value)
+		if cause != nil {
+			return &commandFailure{result: result, err: cause}
+		}
+		return c.replyResult(ctx, result)
+	}
+
+	idempotencyKey := commandIdempotencyPrefix + key
+	hash := sha256.Sum256(message.Value)
+	requestHash := hex.EncodeToString(hash[:])
+
+	record, err := c.service.Idempotency.Begin(ctx, idempotencyKey, requestHash)
+	if err != nil {
+		if errors.Is(err, repoerrs.ErrIdempotencyConflict) {
+			c.logger.Warnf("Команда с ключом %s отклонена: %v", key, err)
+			return c.replyResult(ctx, entity.CommandResult{Key: key, ErrorCode: "conflict", Error: err.Error()})
+		}
+		return err
+	}
+
+	if record != nil {
+		c.logger.Infof("Команда с ключом %s уже обработана, повторная отправка результата", key)
+		var envelope event.Envelope
+		if err := json.Unmarshal(record.Response, &envelope); err != nil {
+			return err
+		}
+		return c.reply(ctx, envelope)
+	}
+
+	result, cause := c.execute(ctx, message.Value)
+	result.Key = key
+	statusCode := commandStatusCode(result)
+
+	storeCtx := context.WithoutCancel(ctx)
+	if cause != nil {
+		c.service.Idempotency.Release(storeCtx, idempotencyKey)
+		return &commandFailure{result: result, err: cause}
+	}
+
+	envelope, err := commandResultEvent(result)
+	if err != nil {
+		c.service.Idempotency.Release(storeCtx, idempotencyKey)
+		return err
+	}
+	response, err := json.Marshal(envelope)
+	if err != nil {
+		c.service.Idempotency.Release(storeCtx, idempotencyKey)
+		return err
+	}
+	if err := c.service.Idempotency.Complete(storeCtx, idempotencyKey, statusCode, "application/json", response); err != nil {
+		return err
+	}
+
+	return c.reply(ctx, envelope)
+}
+
+func (c *CommandConsumer) replyResult(ctx context.Context, result entity.CommandResult) error {
+	envelope, err := commandResultEvent(result)
+	if err != nil {
+		return err
+	}
+	return c.reply(ctx, envelope)
+}
+
+func (c *CommandConsumer) reply(ctx context.Context, envelope event.Envelope) error {
+	if err := c.events.Publish(context.WithoutCancel(ctx), c.replyTopic, envelope); err != nil {
+		c.logger.Errorf("Не удалось отправить результат команды в топик %s: %v", c.replyTopic, err)
+		return fmt.Errorf("%w: %w", errReplyFailed, err)
+	}
+	return nil
+}
+
+func commandResultEvent(result entity.CommandResult) (event.Envelope, error) {
+	partitionKey := result.Key
+	if result.AccountId != 0 {
+		partitionKey = event.AccountKey(result.AccountId)
+	}
+
+	return event.New(event.TypeCommandResult, partitionKey, time.Now(), event.CommandProcessed{
+		Key:           result.Key,
+		Type:          string(result.Type),
+		Success:       result.Success,
+		AccountId:     result.AccountId,
+		ToAccountId:   result.ToAccountId,
+		ReservationId: result.ReservationId,
+		Balance:       result.Balance,
+		ToBalance:     result.ToBalance,
+		ErrorCode:     result.ErrorCode,
+		Error:         result.Error,
+	})
+}
+
+func (c *CommandConsumer) execute(ctx context.Context, value []byte) (entity.CommandResult, error) {
+	var command entity.Command
+	if err := json.Unmarshal(value, &command); err != nil {
+		c.logger.Warnf("Не удалось разобрать команду: %v", err)
+		return entity.CommandResult{ErrorCode: "invalid", Error: fmt.Sprintf("некорректный формат команды: %v", err)}, nil
+	}
+
+	result := entity.CommandResult{
+		Type:          command.Type,
+		AccountId:     command.AccountId,
+		ToAccountId:   command.ToAccountId,
+		ReservationId: command.ReservationId,
+	}
+
+	err := c.dispatch(ctx, command, &result)
+	if err != nil {
+		c.logger.Warnf("Команда %s не выполнена: %v", command.Type, err)
+		result.ErrorCode = commandErrorCode(err)
+		result.Error = err.Error()
+		if commandStatusCode(result) >= http.StatusInternalServerError {
+			return result, err
+		}
+		return result, nil
+	}
+
+	c.logger.Infof("Команда %s успешно выполнена", command.Type)
+	result.Success = true
+	return result, nil
+}
+
+func (c *CommandConsumer) dispatch(ctx context.Context, command entity.Command, result *entity.CommandResult) error {
+	switch command.Type {
+	case entity.CommandDeposit, entity.CommandWithdraw, entity.CommandTransfer:
+		if command.Amount <= 0 {
+			return repoerrs.ErrInvalidAmount
+		}
+	}
+
+	switch command.Type {
+	case entity.CommandDeposit:
+		_, balance, err := c.service.Account.Deposit(ctx, command.AccountId, command.Amount)
+		if err != nil {
+			return err
+		}
+		result.Balance = &balance
+	case entity.CommandWithdraw:
+		_, balance, err := c.service.Account.Withdraw(ctx, command.AccountId, command.Amount)
+		if err != nil {
+			return err
+		}
+		result.Balance = &balance
+	case entity.CommandTransfer:
+		fromBalance, toBalance, err := c.service.Account.Transfer(ctx, command.AccountId, command.ToAccountId, command.Amount)
+		if err != nil {
+			return err
+		}
+		result.Balance = &fromBalance
+		result.ToBalance = &toBalance
+	case entity.CommandReserve:
+		id, err := c.service.Reservation.CreateReservation(ctx, entity.Reservation{
+			AccountId: command.AccountId,
+			ProductId: command.ProductId,
+			OrderId:   command.OrderId,
+			Amount:    command.Amount,
+		})
+		if err != nil {
+			return err
+		}
+		result.ReservationId = id
+	case entity.CommandConfirm:
+		if command.Amount > 0 {
+			return c.service.Reservation.CaptureReservation(ctx, command.ReservationId, command.Amount)
+		}
+		return c.service.Reservation.ConfirmReservation(ctx, command.ReservationId)
+	case entity.CommandRefund:
+		if command.Amount > 0 {
+			return c.service.Reservation.PartialRefundReservation(ctx, command.ReservationId, command.Amount)
+		}
+		return c.service.Reservation.RefundReservation(ctx, command.ReservationId)
+	default:
+		return fmt.Errorf("%w: %q", errUnknownCommand, command.Type)
+	}
+	return nil
+}
+
+func failedCommandResult(message *sarama.ConsumerMessage, err error) entity.CommandResult {
+	var failure *commandFailure
+	if errors.As(err, &failure) {
+		return failure.result
+	}
+	return entity.CommandResult{Key: string(message.Key), ErrorCode: "internal", Error: err.Error()}
+}
+
+func isTransientError(err error) bool {
+	if errors.Is(err, errReplyFailed) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "08", "40", "53", "57":
+			return true
+		}
+	}
+	return false
+}
+
+func commandErrorCode(err error) string {
+	switch {
+	case errors.Is(err, errUnknownCommand),
+		errors.Is(err, repoerrs.ErrInvalidAmount),
+		errors.Is(err, repoerrs.ErrInvalidOrder):
+		return "invalid"
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, repoerrs.ErrDataDeleted):
+		return "not_found"
+	case errors.Is(err, repoerrs.ErrNotEnoughBalance):
+		return "insufficient_funds"
+	case errors.Is(err, repoerrs.ErrReservationConfirmed),
+		errors.Is(err, repoerrs.ErrReservationRefunded),
+		errors.Is(err, repoerrs.ErrReservationExpired),
+		errors.Is(err, repoerrs.ErrIllegalStatusTransition),
+		errors.Is(err, repoerrs.ErrOrderReservationExists):
+		return "conflict"
+	default:
+		return "internal"
+	}
+}
+
+func commandStatusCode(result entity.CommandResult) int {
+	switch result.ErrorCode {
+	case "":
+		return http.StatusOK
+	case "invalid":
+		return http.StatusBadRequest
+	case "not_found":
+		return http.StatusNotFound
+	case "insufficient_funds", "conflict":
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,288 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+	"user_balance/internal/entity"
+	"user_balance/internal/repository/repoerrs"
+	"user_balance/internal/service"
+	"user_balance/pkg/event"
+
+	"github.com/IBM/sarama"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeAccountService struct {
+	service.Account
+	errs  []error
+	calls int
+}
+
+func (f *fakeAccountService) Deposit(ctx context.Context, id, amount int) (int, int, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		if len(f.errs) > 1 {
+			f.errs = f.errs[1:]
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return id, amount, nil
+}
+
+type fakeIdempotencyService struct {
+	records  map[string]*entity.IdempotencyRecord
+	released int
+}
+
+func (f *fakeIdempotencyService) Begin(ctx context.Context, key, requestHash string) (*entity.IdempotencyRecord, error) {
+	record, ok := f.records[key]
+	if !ok {
+		f.records[key] = &entity.IdempotencyRecord{Key: key, RequestHash: requestHash}
+		return nil, nil
+	}
+	if record.RequestHash != requestHash {
+		return nil, repoerrs.ErrIdempotencyConflict
+	}
+	if record.CompletedAt == nil {
+		return nil, repoerrs.ErrIdempotencyInProgress
+	}
+	return record, nil
+}
+
+func (f *fakeIdempotencyService) Complete(ctx context.Context, key string, statusCode int, contentType string, response []byte) error {
+	now := time.Now()
+	record := f.records[key]
+	record.StatusCode = &statusCode
+	record.Response = response
+	record.CompletedAt = &now
+	return nil
+}
+
+func (f *fakeIdempotencyService) Release(ctx context.Context, key string) error {
+	f.released++
+	delete(f.records, key)
+	return nil
+}
+
+func (f *fakeIdempotencyService) Cleanup(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+type fakePublisher struct {
+	failures  int
+	published []event.Envelope
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, topic string, envelope event.Envelope) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("брокер недоступен")
+	}
+	f.published = append(f.published, envelope)
+	return nil
+}
+
+func (f *fakePublisher) Close() error {
+	return nil
+}
+
+func TestCommandConsumerHandleWithRetry(t *testing.T) {
+	checkViolation := &pq.Error{Code: "23514", Message: "new row violates check constraint"}
+	serialization := &pq.Error{Code: "40001", Message: "could not serialize access"}
+
+	tests := []struct {
+		name           string
+		key            string
+		command        entity.Command
+		depositErrs    []error
+		publishFails   int
+		deliveries     int
+		timeout        time.Duration
+		wantErr        bool
+		wantCalls      int
+		wantReplies    int
+		wantSuccess    bool
+		wantErrorCode  string
+		wantReleased   int
+		wantStoredKeys int
+	}{
+		{
+			name:           "успешная команда сохраняется по ключу",
+			key:            "c1",
+			command:        entity.Command{Type: entity.CommandDeposit, AccountId: 1, Amount: 100},
+			deliveries:     1,
+			wantCalls:      1,
+			wantReplies:    1,
+			wantSuccess:    true,
+			wantStoredKeys: 1,
+		},
+		{
+			name:           "повторная доставка не выполняет команду снова",
+			key:            "c2",
+			command:        entity.Command{Type: entity.CommandDeposit, AccountId: 1, Amount: 100},
+			deliveries:     2,
+			wantCalls:      1,
+			wantReplies:    2,
+			wantSuccess:    true,
+			wantStoredKeys: 1,
+		},
+		{
+			name:           "неположительная сумма отклоняется без вызова сервиса",
+			key:            "c3",
+			command:        entity.Command{Type: entity.CommandDeposit, AccountId: 1, Amount: -5},
+			deliveries:     1,
+			wantReplies:    1,
+			wantErrorCode:  "invalid",
+			wantStoredKeys: 1,
+		},
+		{
+			name:          "постоянная ошибка завершается отказом после лимита попыток",
+			key:           "c4",
+			command:       entity.Command{Type: entity.CommandDeposit, AccountId: 1, Amount: 100},
+			depositErrs:   []error{checkViolation},
+			deliveries:    1,
+			wantCalls:     commandMaxAttempts,
+			wantReplies:   1,
+			wantErrorCode: "internal",
+			wantReleased:  commandMaxAttempts,
+		},
+		{
+			name:           "временная ошибка повторяется до успеха",
+			key:            "c5",
+			command:        entity.Command{Type: entity.CommandDeposit, AccountId: 1, Amount: 100},
+			depositErrs:    []error{serialization, serialization, nil},
+			deliveries:     1,
+			wantCalls:      3,
+			wantReplies:    1,
+			wantSuccess:    true,
+			wantReleased:   2,
+			wantStoredKeys: 1,
+		},
+		{
+			name:        "временная ошибка не ограничена числом попыток",
+			key:         "c6",
+			command:     entity.Command{Type: entity.CommandDeposit, AccountId: 1, Amount: 100},
+			depositErrs: []error{serialization},
+			deliveries:  1,
+			timeout:     50 * time.Millisecond,
+			wantErr:     true,
+		},
+		{
+			name:           "ошибка отправки ответа повторяет сохранённый результат",
+			key:            "c7",
+			command:        entity.Command{Type: entity.CommandDeposit, AccountId: 1, Amount: 100},
+			publishFails:   commandMaxAttempts + 1,
+			deliveries:     1,
+			wantCalls:      1,
+			wantReplies:    1,
+			wantSuccess:    true,
+			wantStoredKeys: 1,
+		},
+		{
+			name:          "команда без ключа с постоянной ошибкой",
+			command:       entity.Command{Type: entity.CommandDeposit, AccountId: 1, Amount: 100},
+			depositErrs:   []error{checkViolation},
+			deliveries:    1,
+			wantCalls:     commandMaxAttempts,
+			wantReplies:   1,
+			wantErrorCode: "internal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accounts := &fakeAccountService{errs: tt.depositErrs}
+			idempotency := &fakeIdempotencyService{records: map[string]*entity.IdempotencyRecord{}}
+			events := &fakePublisher{failures: tt.publishFails}
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+
+			consumer := &CommandConsumer{
+				service:      &service.Service{Account: accounts, Idempotency: idempotency},
+				events:       events,
+				replyTopic:   "replies",
+				retryBackoff: time.Millisecond,
+				logger:       logger,
+			}
+
+			value, err := json.Marshal(tt.command)
+			if err != nil {
+				t.Fatal(err)
+			}
+			message := &sarama.ConsumerMessage{Topic: "commands", Key: []byte(tt.key), Value: value}
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			for i := 0; i < tt.deliveries; i++ {
+				err = consumer.handleWithRetry(ctx, message)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("доставка %d: ошибка %v, ожидалась ошибка: %v", i+1, err, tt.wantErr)
+				}
+			}
+
+			if !tt.wantErr && accounts.calls != tt.wantCalls {
+				t.Errorf("сервис вызван %d раз, ожидалось %d", accounts.calls, tt.wantCalls)
+			}
+			if len(events.published) != tt.wantReplies {
+				t.Fatalf("отправлено %d ответов, ожидалось %d", len(events.published), tt.wantReplies)
+			}
+			if idempotency.released != tt.wantReleased && !tt.wantErr {
+				t.Errorf("ключ освобождён %d раз, ожидалось %d", idempotency.released, tt.wantReleased)
+			}
+			if len(idempotency.records) != tt.wantStoredKeys && !tt.wantErr {
+				t.Errorf("сохранено ключей %d, ожидалось %d", len(idempotency.records), tt.wantStoredKeys)
+			}
+
+			for _, envelope := range events.published {
+				var reply event.CommandProcessed
+				if err := envelope.Decode(&reply); err != nil {
+					t.Fatal(err)
+				}
+				if reply.Key != tt.key || reply.Success != tt.wantSuccess || reply.ErrorCode != tt.wantErrorCode {
+					t.Errorf("ответ %+v, ожидались key=%q success=%v error_code=%q", reply, tt.key, tt.wantSuccess, tt.wantErrorCode)
+				}
+			}
+			if tt.deliveries > 1 && events.published[0].Id != events.published[1].Id {
+				t.Errorf("повторный ответ %s отличается от исходного %s", events.published[1].Id, events.published[0].Id)
+			}
+		})
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "нарушение ограничения", err: &pq.Error{Code: "23514"}, want: false},
+		{name: "ошибка сериализации", err: &pq.Error{Code: "40001"}, want: true},
+		{name: "взаимоблокировка", err: &pq.Error{Code: "40P01"}, want: true},
+		{name: "потеря соединения", err: &pq.Error{Code: "08006"}, want: true},
+		{name: "отмена по таймауту", err: &pq.Error{Code: "57014"}, want: true},
+		{name: "ошибка отправки ответа", err: errReplyFailed, want: true},
+		{name: "обёрнутая ошибка сервиса", err: &commandFailure{err: &pq.Error{Code: "40001"}}, want: true},
+		{name: "неизвестная ошибка", err: errors.New("ошибка"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientError(tt.err); got != tt.want {
+				t.Errorf("isTransientError(%v) = %v, ожидалось %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"user_balance/config"
+
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+)
+
+func KafkaBrokers(cfg config.Kafka) []string {
+	var brokers []string
+	for _, broker := range strings.Split(cfg.Brokers, ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	return brokers
+}
+
+func NewSaramaConfig(cfg config.Kafka) (*sarama.Config, error) {
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.ClientID = cfg.ClientID
+
+	if cfg.Version != "" {
+		version, err := sarama.ParseKafkaVersion(cfg.Version)
+		if err != nil {
+			return nil, fmt.Errorf("некорректная версия Kafka %q: %w", cfg.Version, err)
+		}
+		saramaConfig.Version = version
+	}
+
+	acks, err := parseRequiredAcks(cfg.RequiredAcks)
+	if err != nil {
+		return nil, err
+	}
+	saramaConfig.Producer.RequiredAcks = acks
+	saramaConfig.Producer.Return.Successes = true
+	saramaConfig.Producer.Retry.Max = cfg.MaxRetries
+	saramaConfig.Producer.Retry.Backoff = cfg.RetryBackoff
+
+	compression, err := parseCompression(cfg.Compression)
+	if err != nil {
+		return nil, err
+	}
+	saramaConfig.Producer.Compression = compression
+
+	if cfg.Idempotent {
+		if acks != sarama.WaitForAll {
+			return nil, errors.New("идемпотентный producer требует KAFKA_REQUIRED_ACKS=all")
+		}
+		saramaConfig.Producer.Idempotent = true
+		saramaConfig.Net.MaxOpenRequests = 1
+	}
+
+	if cfg.TLS.Enabled {
+		tlsConfig, err := newKafkaTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		saramaConfig.Net.TLS.Enable = true
+		saramaConfig.Net.TLS.Config = tlsConfig
+	}
+
+	if cfg.SASL.Mechanism != "" {
+		if err := configureSASL(saramaConfig, cfg.SASL); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := saramaConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("некорректная конфигурация Kafka: %w", err)
+	}
+
+	return saramaConfig, nil
+}
+
+func parseRequiredAcks(value string) (sarama.RequiredAcks, error) {
+	switch strings.ToLower(value) {
+	case "none", "0":
+		return sarama.NoResponse, nil
+	case "leader", "1":
+		return sarama.WaitForLocal, nil
+	case "all", "-1":
+		return sarama.WaitForAll, nil
+	default:
+		return 0, fmt.Errorf("неподдерживаемое значение KAFKA_REQUIRED_ACKS: %s", value)
+	}
+}
+
+func parseCompression(value string) (sarama.CompressionCodec, error) {
+	switch strings.ToLower(value) {
+	case "", "none":
+		return sarama.CompressionNone, nil
+	case "gzip":
+		return sarama.CompressionGZIP, nil
+	case "snappy":
+		return sarama.CompressionSnappy, nil
+	case "lz4":
+		return sarama.CompressionLZ4, nil
+	case "zstd":
+		return sarama.CompressionZSTD, nil
+	default:
+		return 0, fmt.Errorf("неподдерживаемый кодек сжатия Kafka: %s", value)
+	}
+}
+
+func newKafkaTLSConfig(cfg config.KafkaTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		caCert, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения CA сертификата Kafka: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("не удалось разобрать CA сертификат Kafka: %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка загрузки клиентского сертификата Kafka: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func configureSASL(saramaConfig *sarama.Config, cfg config.KafkaSASL) error {
+	saramaConfig.Net.SASL.Enable = true
+	saramaConfig.Net.SASL.User = cfg.User
+	saramaConfig.Net.SASL.Password = cfg.Password
+
+	switch strings.ToUpper(cfg.Mechanism) {
+	case sarama.SASLTypePlaintext:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case sarama.SASLTypeSCRAMSHA256:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hashGenerator: scram.SHA256}
+		}
+	case sarama.SASLTypeSCRAMSHA512:
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hashGenerator: scram.SHA512}
+		}
+	default:
+		return fmt.Errorf("неподдерживаемый механизм SASL: %s", cfg.Mechanism)
+	}
+
+	return nil
+}
+
+type scramClient struct {
+	hashGenerator scram.HashGeneratorFcn
+	conversation  *scram.ClientConversation
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.hashGenerator.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conversation = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.conversation.Done()
+}
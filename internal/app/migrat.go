@@ -1,39 +1,113 @@
 package app
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"io"
 	"log"
-	"os"
-	"path/filepath"
-	"strings"
+	"text/tabwriter"
+
+	"user_balance/internal/migrator"
+	"user_balance/migration"
 )
 
-func ApplyMigrations(db *sql.DB, migrationsPath string) error {
-	log.Printf("Чтение директории с миграциями: %s", migrationsPath)
-	files, err := os.ReadDir(migrationsPath)
+func ApplyMigrations(db *sql.DB) error {
+	m, err := migrator.New(db, migration.FS)
+	if err != nil {
+		return err
+	}
+
+	applied, err := m.Up(context.Background(), false)
 	if err != nil {
 		return err
 	}
 
-	for _, file := range files {
-		if !strings.HasSuffix(file.Name(), ".sql") {
-			continue
+	log.Printf("Применено новых миграций: %d", len(applied))
+	return nil
+}
+
+func RunMigrate(command string, steps int, dryRun bool, out io.Writer) error {
+	cfg, logger := loadConfig()
+
+	db := openDatabase(cfg, logger)
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Errorf("Ошибка при закрытии подключения к базе данных: %v", err)
 		}
+	}()
 
-		filePath := filepath.Join(migrationsPath, file.Name())
-		log.Printf("Чтение файла миграции: %s", file.Name())
-		query, err := os.ReadFile(filePath)
+	m, err := migrator.New(db, migration.FS)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		applied, err := m.Up(ctx, dryRun)
 		if err != nil {
 			return err
 		}
-
-		log.Printf("Применение миграции: %s", file.Name())
-		_, err = db.Exec(string(query))
+		return printMigrations(out, "up", applied, dryRun)
+	case "down":
+		if steps < 1 {
+			return fmt.Errorf("количество шагов отката должно быть положительным: %d", steps)
+		}
+		rolledBack, err := m.Down(ctx, steps, dryRun)
 		if err != nil {
 			return err
 		}
+		return printMigrations(out, "down", rolledBack, dryRun)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if errors.Is(err, migrator.ErrNoMigrationsTable) {
+			fmt.Fprintln(out, "Таблица миграций отсутствует: ни одна миграция не применена")
+		} else if err != nil {
+			return err
+		}
+		return printMigrationStatus(out, statuses)
+	default:
+		return fmt.Errorf("неизвестная команда migrate: %q (ожидается up, down или status)", command)
+	}
+}
 
-		log.Printf("Миграция успешно применена: %s", file.Name())
+func printMigrations(out io.Writer, direction string, migrations []migrator.Migration, dryRun bool) error {
+	prefix := ""
+	if dryRun {
+		prefix = "[dry-run] "
+	}
+	if len(migrations) == 0 {
+		_, err := fmt.Fprintf(out, "%sНет миграций для выполнения (%s)\n", prefix, direction)
+		return err
+	}
+	for _, m := range migrations {
+		if _, err := fmt.Fprintf(out, "%s%s %04d_%s\n", prefix, direction, m.Version, m.Name); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+func printMigrationStatus(out io.Writer, statuses []migrator.Status) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		status := "pending"
+		appliedAt := "-"
+		switch {
+		case s.Missing:
+			status = "missing file"
+		case s.Dirty:
+			status = "checksum mismatch"
+		case s.Applied:
+			status = "applied"
+		}
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"user_balance/internal/entity"
+	"user_balance/internal/publisher"
+	"user_balance/internal/repository"
+	"user_balance/pkg/event"
+
+	"github.com/sirupsen/logrus"
+)
+
+type OutboxRelay struct {
+	outbox    repository.Outbox
+	events    publisher.EventPublisher
+	topic     string
+	interval  time.Duration
+	batchSize int
+	lease     time.Duration
+	logger    *logrus.Logger
+}
+
+func NewOutboxRelay(outbox repository.Outbox, events publisher.EventPublisher, topic string, interval time.Duration, batchSize int, lease time.Duration, logger *logrus.Logger) *OutboxRelay {
+	return &OutboxRelay{
+		outbox:    outbox,
+		events:    events,
+		topic:     topic,
+		interval:  interval,
+		batchSize: batchSize,
+		lease:     lease,
+		logger:    logger,
+	}
+}
+
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.logger.Info("Outbox relay остановлен.")
+			return
+		case <-ticker.C:
+			r.flush(ctx)
+		}
+	}
+}
+
+func (r *OutboxRelay) flush(ctx context.Context) {
+	for {
+		sent, err := r.outbox.ProcessOutbox(ctx, r.batchSize, r.lease, func(message entity.OutboxMessage) error {
+			envelope, err := outboxEnvelope(message)
+			if err != nil {
+				return err
+			}
+			return r.events.Publish(ctx, r.topic, envelope)
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logger.Errorf("Ошибка обработки outbox: %v", err)
+			}
+			return
+		}
+		if sent > 0 {
+			r.logger.Debugf("Outbox: отправлено событий: %d", sent)
+		}
+		if sent < r.batchSize {
+			return
+		}
+	}
+}
+
+func outboxEnvelope(message entity.OutboxMessage) (event.Envelope, error) {
+	var envelope event.Envelope
+	if err := json.Unmarshal(message.Payload, &envelope); err != nil {
+		return event.Envelope{}, err
+	}
+	if envelope.SpecVersion != "" {
+		return envelope, nil
+	}
+
+	var operation entity.Operation
+	if err := json.Unmarshal(message.Payload, &operation); err != nil {
+		return event.Envelope{}, err
+	}
+	return event.New(event.OperationType(operation.OperationType), event.AccountKey(message.AccountId), message.CreatedAt, event.OperationRecorded{
+		OperationId:   operation.Id,
+		AccountId:     operation.AccountId,
+		OperationType: operation.OperationType,
+		Amount:        operation.Amount,
+		BalanceEffect: operation.BalanceEffect(),
+		ProductId:     operation.ProductId,
+		CreatedAt:     operation.CreatedAt,
+	})
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"user_balance/config"
+	"user_balance/internal/publisher"
+)
+
+func NewEventPublisher(cfg config.Events, kafka config.Kafka) (publisher.EventPublisher, error) {
+	switch cfg.Sink {
+	case "kafka":
+		brokers := KafkaBrokers(kafka)
+		if len(brokers) == 0 {
+			return nil, errors.New("не заданы брокеры Kafka (KAFKA_BROKERS)")
+		}
+		saramaConfig, err := NewSaramaConfig(kafka)
+		if err != nil {
+			return nil, err
+		}
+		encoder, err := publisher.NewEncoder(cfg.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		return publisher.NewKafkaPublisher(brokers, saramaConfig, encoder)
+	case "memory":
+		return publisher.NewMemoryPublisher(), nil
+	case "file":
+		return publisher.NewFilePublisher(cfg.File)
+	default:
+		return nil, fmt.Errorf("неподдерживаемый приёмник событий: %s", cfg.Sink)
+	}
+}
@@ -2,29 +2,271 @@ package app
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
+	"user_balance/internal/entity"
+	"user_balance/internal/publisher"
 	"user_balance/internal/repository"
+	"user_balance/internal/repository/repoerrs"
+	"user_balance/internal/service"
+	"user_balance/pkg/event"
 
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	MonthlyReportJobName      = "monthly-revenue-report"
+	ExpireReservationsJobName = "expire-reservations"
+	PruneJobRunsJobName       = "prune-job-runs"
+	CleanupIdempotencyJobName = "cleanup-idempotency-keys"
+	defaultJobRunsLimit       = 20
+)
+
+type JobFunc func(ctx context.Context, args entity.JobArgs) (int, error)
+
+type Job struct {
+	Name    string
+	Spec    string
+	Run     JobFunc
+	entryID cron.EntryID
+}
+
 type Scheduler struct {
-	cron   *cron.Cron
-	logger *logrus.Logger
+	cron     *cron.Cron
+	jobs     map[string]*Job
+	runs     repository.Job
+	instance string
+	running  sync.WaitGroup
+	logger   *logrus.Logger
 }
 
-func NewScheduler(logger *logrus.Logger) *Scheduler {
+func NewScheduler(runs repository.Job, logger *logrus.Logger) *Scheduler {
+	instance, err := os.Hostname()
+	if err != nil {
+		instance = "unknown"
+	}
+	instance = fmt.Sprintf("%s-%d", instance, os.Getpid())
+
 	return &Scheduler{
-		cron:   cron.New(),
-		logger: logger,
+		cron:     cron.New(),
+		jobs:     make(map[string]*Job),
+		runs:     runs,
+		instance: instance,
+		logger:   logger,
+	}
+}
+
+func (s *Scheduler) Register(name, spec string, run JobFunc) error {
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("задача %s уже зарегистрирована", name)
+	}
+
+	job := &Job{Name: name, Spec: spec, Run: run}
+	entryID, err := s.cron.AddFunc(spec, func() {
+		_, err := s.execute(context.Background(), job, entity.JobTriggerSchedule, time.Now().Truncate(time.Minute), nil)
+		if errors.Is(err, repoerrs.ErrJobRunning) || errors.Is(err, repoerrs.ErrJobAlreadyRun) {
+			s.logger.Infof("Плановый запуск задачи %s пропущен: %v", job.Name, err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	job.entryID = entryID
+	s.jobs[name] = job
+
+	s.logger.Infof("Задача %s зарегистрирована с расписанием %q", name, spec)
+	return nil
+}
+
+func (s *Scheduler) ListJobs(ctx context.Context) ([]entity.JobInfo, error) {
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	jobs := make([]entity.JobInfo, 0, len(names))
+	for _, name := range names {
+		job := s.jobs[name]
+		info := entity.JobInfo{Name: job.Name, Schedule: job.Spec}
+
+		entry := s.cron.Entry(job.entryID)
+		if !entry.Next.IsZero() {
+			next := entry.Next
+			info.NextRunAt = &next
+		}
+		if !entry.Prev.IsZero() {
+			prev := entry.Prev
+			info.PrevRunAt = &prev
+		}
+
+		runs, err := s.runs.ListJobRuns(ctx, job.Name, 1)
+		if err != nil {
+			err = fmt.Errorf("ошибка при получении истории задачи %s: %w", job.Name, err)
+			s.logger.Error(err)
+			return nil, err
+		}
+		if len(runs) > 0 {
+			info.LastRun = &runs[0]
+		}
+
+		jobs = append(jobs, info)
+	}
+
+	return jobs, nil
+}
+
+func (s *Scheduler) ListJobRuns(ctx context.Context, name string, limit int) ([]entity.JobRun, error) {
+	if _, ok := s.jobs[name]; !ok {
+		return nil, repoerrs.ErrJobNotFound
+	}
+	if limit <= 0 {
+		limit = defaultJobRunsLimit
+	}
+
+	runs, err := s.runs.ListJobRuns(ctx, name, limit)
+	if err != nil {
+		err = fmt.Errorf("ошибка при получении истории задачи %s: %w", name, err)
+		s.logger.Error(err)
+		return nil, err
+	}
+	return runs, nil
+}
+
+func (s *Scheduler) TriggerJob(ctx context.Context, name string) (entity.JobRun, error) {
+	job, ok := s.jobs[name]
+	if !ok {
+		return entity.JobRun{}, repoerrs.ErrJobNotFound
+	}
+
+	s.logger.Infof("Ручной запуск задачи %s", name)
+	return s.dispatch(ctx, job, entity.JobTriggerManual, time.Now(), nil)
+}
+
+func (s *Scheduler) BackfillMonthlyReport(ctx context.Context, year int, month time.Month, force bool) (entity.JobRun, error) {
+	job, ok := s.jobs[MonthlyReportJobName]
+	if !ok {
+		return entity.JobRun{}, repoerrs.ErrJobNotFound
+	}
+
+	now := time.Now()
+	if !time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Before(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)) {
+		return entity.JobRun{}, repoerrs.ErrInvalidPeriod
+	}
+
+	s.logger.Infof("Перезапуск отчета о выручке за %02d.%d", month, year)
+	args := entity.JobArgs{
+		"year":  strconv.Itoa(year),
+		"month": strconv.Itoa(int(month)),
+	}
+	if force {
+		args["force"] = "true"
+	}
+	return s.dispatch(ctx, job, entity.JobTriggerBackfill, now, args)
+}
+
+func (s *Scheduler) execute(ctx context.Context, job *Job, trigger entity.JobTrigger, scheduledAt time.Time, args entity.JobArgs) (entity.JobRun, error) {
+	_, finish, err := s.start(ctx, job, trigger, scheduledAt, args)
+	if err != nil {
+		return entity.JobRun{}, err
+	}
+	return finish()
+}
+
+func (s *Scheduler) dispatch(ctx context.Context, job *Job, trigger entity.JobTrigger, scheduledAt time.Time, args entity.JobArgs) (entity.JobRun, error) {
+	run, finish, err := s.start(ctx, job, trigger, scheduledAt, args)
+	if err != nil {
+		return entity.JobRun{}, err
+	}
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		finish()
+	}()
+	return run, nil
+}
+
+func (s *Scheduler) start(ctx context.Context, job *Job, trigger entity.JobTrigger, scheduledAt time.Time, args entity.JobArgs) (entity.JobRun, func() (entity.JobRun, error), error) {
+	ctx = context.WithoutCancel(ctx)
+
+	unlock, locked, err := s.runs.LockJob(ctx, job.Name)
+	if err != nil {
+		err = fmt.Errorf("ошибка получения блокировки задачи %s: %w", job.Name, err)
+		s.logger.Error(err)
+		return entity.JobRun{}, nil, err
+	}
+	if !locked {
+		return entity.JobRun{}, nil, repoerrs.ErrJobRunning
 	}
+
+	stale, err := s.runs.FailStaleJobRuns(ctx, job.Name, "запуск прерван: экземпляр остановлен до завершения задачи")
+	if err != nil {
+		s.logger.WithError(err).Errorf("Ошибка пометки незавершённых запусков задачи %s", job.Name)
+	} else if stale > 0 {
+		s.logger.Warnf("Незавершённые запуски задачи %s помечены как неуспешные: %d", job.Name, stale)
+	}
+
+	runID, started, err := s.runs.StartJobRun(ctx, job.Name, trigger, scheduledAt, s.instance, args)
+	if err != nil {
+		unlock()
+		err = fmt.Errorf("ошибка регистрации запуска задачи %s: %w", job.Name, err)
+		s.logger.Error(err)
+		return entity.JobRun{}, nil, err
+	}
+	if !started {
+		unlock()
+		return entity.JobRun{}, nil, repoerrs.ErrJobAlreadyRun
+	}
+
+	run, err := s.runs.GetJobRun(ctx, runID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Ошибка получения запуска задачи %s", job.Name)
+		run = entity.JobRun{Id: runID, JobName: job.Name, Trigger: trigger, ScheduledAt: scheduledAt, Parameters: args, Instance: s.instance, Status: entity.JobRunRunning}
+	}
+
+	finish := func() (entity.JobRun, error) {
+		defer unlock()
+		return s.finish(ctx, job, runID, args)
+	}
+	return run, finish, nil
 }
 
-func (s *Scheduler) AddJob(spec string, job func()) error {
-	_, err := s.cron.AddFunc(spec, job)
-	return err
+func (s *Scheduler) finish(ctx context.Context, job *Job, runID int64, args entity.JobArgs) (entity.JobRun, error) {
+	startedAt := time.Now()
+	rows, runErr := job.Run(ctx, args)
+
+	status := entity.JobRunSucceeded
+	var reason *string
+	if runErr != nil {
+		status = entity.JobRunFailed
+		message := runErr.Error()
+		reason = &message
+		s.logger.WithError(runErr).Errorf("Задача %s завершилась с ошибкой", job.Name)
+	} else {
+		s.logger.Infof("Задача %s выполнена за %s, обработано записей: %d", job.Name, time.Since(startedAt), rows)
+	}
+
+	if err := s.runs.FinishJobRun(ctx, runID, status, rows, reason); err != nil {
+		err = fmt.Errorf("ошибка сохранения результата задачи %s: %w", job.Name, err)
+		s.logger.Error(err)
+		return entity.JobRun{}, err
+	}
+
+	run, err := s.runs.GetJobRun(ctx, runID)
+	if err != nil {
+		err = fmt.Errorf("ошибка получения результата задачи %s: %w", job.Name, err)
+		s.logger.Error(err)
+		return entity.JobRun{}, err
+	}
+	return run, nil
 }
 
 func (s *Scheduler) Start() {
@@ -33,46 +275,165 @@ func (s *Scheduler) Start() {
 }
 
 func (s *Scheduler) Stop() {
-	s.cron.Stop()
+	<-s.cron.Stop().Done()
+	s.running.Wait()
 	s.logger.Info("Cron scheduler остановлен")
 }
 
-func (s *Scheduler) GenerateMonthlyReportJob(repo *repository.Repository, producer *Producer, topic string) func() {
-	return func() {
+func (s *Scheduler) GenerateMonthlyReportJob(reports service.Report, events publisher.EventPublisher, topic string) JobFunc {
+	return func(ctx context.Context, args entity.JobArgs) (int, error) {
 		s.logger.Info("Запуск задачи генерации отчета...")
-		ctx := context.Background()
 
-		if repo == nil {
-			s.logger.Warn("Ошибка: repo равен nil")
-			return
+		if reports == nil {
+			return 0, errors.New("reports равен nil")
+		}
+		if events == nil {
+			return 0, errors.New("events равен nil")
+		}
+
+		year, month, err := reportJobPeriod(args)
+		if err != nil {
+			return 0, err
+		}
+
+		report, err := reports.GetRevenueReport(ctx, year, month)
+		if err != nil {
+			return 0, fmt.Errorf("ошибка формирования отчета: %w", err)
+		}
+
+		var saveErr error
+		if _, err := reports.SaveRevenueReport(ctx, report); err != nil {
+			s.logger.WithError(err).Error("Ошибка сохранения файла отчета")
+			saveErr = fmt.Errorf("ошибка сохранения файла отчета: %w", err)
+		}
+
+		force := args["force"] == "true"
+		published, err := reports.ExportRevenueReport(ctx, report, force, func(report entity.RevenueReport, revision int) error {
+			envelope, err := revenueReportEvent(report, revision)
+			if err != nil {
+				return err
+			}
+			return events.Publish(ctx, topic, envelope)
+		})
+		if err != nil {
+			return published, errors.Join(fmt.Errorf("ошибка публикации отчета: %w", err), saveErr)
 		}
-		if producer == nil {
-			s.logger.Warn("Ошибка: producer равен nil")
-			return
+		if saveErr != nil {
+			return published, saveErr
 		}
+		if published == 0 {
+			s.logger.Infof("Отчет о выручке за %02d.%d уже отправлен, файл отчета обновлен", report.Month, report.Year)
+			return 0, nil
+		}
+
+		s.logger.Infof("Отчет о выручке за %02d.%d отправлен: %d продуктов, итого %d",
+			report.Month, report.Year, len(report.Items), report.Total)
+		return published, nil
+	}
+}
 
+func reportJobPeriod(args entity.JobArgs) (int, time.Month, error) {
+	if args["year"] == "" && args["month"] == "" {
 		now := time.Now()
-		start := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, now.Location())
-		end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		previous := now.AddDate(0, 0, -now.Day())
+		return previous.Year(), previous.Month(), nil
+	}
 
-		s.logger.Infof("Start Date: %v, End Date: %v", start, end)
+	year, err := strconv.Atoi(args["year"])
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: год %q", repoerrs.ErrInvalidPeriod, args["year"])
+	}
+	month, err := strconv.Atoi(args["month"])
+	if err != nil || month < 1 || month > 12 {
+		return 0, 0, fmt.Errorf("%w: месяц %q", repoerrs.ErrInvalidPeriod, args["month"])
+	}
+	return year, time.Month(month), nil
+}
 
-		operations, err := repo.GetMonthlyOperations(ctx, start, end)
+func revenueReportEvent(report entity.RevenueReport, revision int) (event.Envelope, error) {
+	items := make([]event.RevenueReportItem, 0, len(report.Items))
+	for _, item := range report.Items {
+		items = append(items, event.RevenueReportItem{
+			ProductId:   item.ProductId,
+			ProductName: item.ProductName,
+			Revenue:     item.Revenue,
+			Operations:  item.Operations,
+		})
+	}
+
+	partitionKey := fmt.Sprintf("report-%d-%02d", report.Year, report.Month)
+	id := partitionKey
+	if revision > 0 {
+		id = fmt.Sprintf("%s-r%d", partitionKey, revision)
+	}
+	return event.NewWithId(id, event.TypeRevenueReport, partitionKey, report.GeneratedAt, event.RevenueReportGenerated{
+		Year:        report.Year,
+		Month:       report.Month,
+		Total:       report.Total,
+		Items:       items,
+		GeneratedAt: report.GeneratedAt,
+	})
+}
+
+func (s *Scheduler) ExpireReservationsJob(reservations service.Reservation, ttl time.Duration) JobFunc {
+	return func(ctx context.Context, args entity.JobArgs) (int, error) {
+		s.logger.Info("Запуск задачи истечения резерваций...")
+
+		if reservations == nil {
+			return 0, errors.New("reservations равен nil")
+		}
+
+		ids, err := reservations.GetStaleReservations(ctx, ttl)
 		if err != nil {
-			s.logger.WithError(err).Error("Ошибка получения операций")
-			return
+			return 0, fmt.Errorf("ошибка получения просроченных резерваций: %w", err)
 		}
 
-		if len(operations) == 0 {
-			s.logger.Info("Нет операций для отправки.")
-			return
+		if len(ids) == 0 {
+			s.logger.Info("Нет просроченных резерваций.")
+			return 0, nil
 		}
 
-		for _, op := range operations {
-			err := producer.SendMessage(topic, op)
+		expired := 0
+		var errs []error
+		for _, id := range ids {
+			err := reservations.ExpireReservation(ctx, id)
+			if errors.Is(err, repoerrs.ErrReservationConfirmed) ||
+				errors.Is(err, repoerrs.ErrReservationRefunded) ||
+				errors.Is(err, repoerrs.ErrReservationExpired) {
+				continue
+			}
 			if err != nil {
-				s.logger.WithError(err).Error("Ошибка отправки сообщения в Kafka")
+				s.logger.WithError(err).Errorf("Ошибка истечения резервации с ID %d", id)
+				errs = append(errs, fmt.Errorf("ошибка истечения резервации с ID %d: %w", id, err))
+				continue
 			}
+			expired++
+		}
+
+		s.logger.Infof("Истекло резерваций: %d из %d", expired, len(ids))
+		return expired, errors.Join(errs...)
+	}
+}
+
+func (s *Scheduler) PruneJobRunsJob(retention time.Duration) JobFunc {
+	return func(ctx context.Context, args entity.JobArgs) (int, error) {
+		before := time.Now().Add(-retention)
+		deleted, err := s.runs.DeleteJobRunsBefore(ctx, before)
+		if err != nil {
+			return 0, fmt.Errorf("ошибка удаления истории запусков задач: %w", err)
+		}
+
+		s.logger.Infof("Удалено запусков задач старше %s: %d", before.Format(time.DateTime), deleted)
+		return deleted, nil
+	}
+}
+
+func (s *Scheduler) CleanupIdempotencyKeysJob(idempotency service.Idempotency) JobFunc {
+	return func(ctx context.Context, args entity.JobArgs) (int, error) {
+		deleted, err := idempotency.Cleanup(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("ошибка очистки ключей идемпотентности: %w", err)
 		}
+		return deleted, nil
 	}
 }
@@ -0,0 +1,17 @@
+package app
+
+import (
+	"fmt"
+
+	"user_balance/config"
+	"user_balance/internal/storage"
+)
+
+func NewReportStorage(cfg config.Reports) (storage.Storage, error) {
+	switch cfg.Storage {
+	case "local":
+		return storage.NewLocalStorage(cfg.Dir)
+	default:
+		return nil, fmt.Errorf("неподдерживаемое хранилище отчётов: %s", cfg.Storage)
+	}
+}
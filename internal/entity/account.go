@@ -5,7 +5,16 @@ import "time"
 type Account struct {
 	Id        int        `db:"id"`
 	Balance   int        `db:"balance"`
+	Held      int        `db:"held"`
 	CreatedAt time.Time  `db:"created_at"`
 	UpdatedAt *time.Time `db:"updated_at"` // Nullable field
 	DeletedAt *time.Time `db:"deleted_at"` // Nullable field
 }
+
+func (a Account) Available() int {
+	return a.Balance
+}
+
+func (a Account) Total() int {
+	return a.Balance + a.Held
+}
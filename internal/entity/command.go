@@ -0,0 +1,35 @@
+package entity
+
+type CommandType string
+
+const (
+	CommandDeposit  CommandType = "deposit"
+	CommandWithdraw CommandType = "withdraw"
+	CommandTransfer CommandType = "transfer"
+	CommandReserve  CommandType = "reserve"
+	CommandConfirm  CommandType = "confirm"
+	CommandRefund   CommandType = "refund"
+)
+
+type Command struct {
+	Type          CommandType `json:"type"`
+	AccountId     int         `json:"account_id,omitempty"`
+	ToAccountId   int         `json:"to_account_id,omitempty"`
+	ProductId     int         `json:"product_id,omitempty"`
+	OrderId       *int        `json:"order_id,omitempty"`
+	ReservationId int         `json:"reservation_id,omitempty"`
+	Amount        int         `json:"amount,omitempty"`
+}
+
+type CommandResult struct {
+	Key           string      `json:"key,omitempty"`
+	Type          CommandType `json:"type"`
+	Success       bool        `json:"success"`
+	AccountId     int         `json:"account_id,omitempty"`
+	ToAccountId   int         `json:"to_account_id,omitempty"`
+	ReservationId int         `json:"reservation_id,omitempty"`
+	Balance       *int        `json:"balance,omitempty"`
+	ToBalance     *int        `json:"to_balance,omitempty"`
+	ErrorCode     string      `json:"error_code,omitempty"`
+	Error         string      `json:"error,omitempty"`
+}
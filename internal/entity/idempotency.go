@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+type IdempotencyRecord struct {
+	Key         string     `db:"key"`
+	RequestHash string     `db:"request_hash"`
+	StatusCode  *int       `db:"status_code"` // Nullable field
+	ContentType string     `db:"content_type"`
+	Response    []byte     `db:"response"`
+	CreatedAt   time.Time  `db:"created_at"`
+	CompletedAt *time.Time `db:"completed_at"` // Nullable field
+	LockedUntil *time.Time `db:"locked_until"` // Nullable field
+}
@@ -0,0 +1,43 @@
+package entity
+
+import "time"
+
+type JobRunStatus string
+
+const (
+	JobRunRunning   JobRunStatus = "running"
+	JobRunSucceeded JobRunStatus = "succeeded"
+	JobRunFailed    JobRunStatus = "failed"
+)
+
+type JobTrigger string
+
+const (
+	JobTriggerSchedule JobTrigger = "schedule"
+	JobTriggerManual   JobTrigger = "manual"
+	JobTriggerBackfill JobTrigger = "backfill"
+)
+
+type JobArgs map[string]string
+
+type JobRun struct {
+	Id            int64        `json:"id"`
+	JobName       string       `json:"job_name"`
+	Trigger       JobTrigger   `json:"trigger"`
+	ScheduledAt   time.Time    `json:"scheduled_at"`
+	Parameters    JobArgs      `json:"parameters,omitempty"`
+	Instance      string       `json:"instance"`
+	Status        JobRunStatus `json:"status"`
+	RowsProcessed int          `json:"rows_processed"`
+	Error         *string      `json:"error,omitempty"`
+	StartedAt     time.Time    `json:"started_at"`
+	FinishedAt    *time.Time   `json:"finished_at,omitempty"`
+}
+
+type JobInfo struct {
+	Name      string     `json:"name"`
+	Schedule  string     `json:"schedule"`
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	PrevRunAt *time.Time `json:"prev_run_at,omitempty"`
+	LastRun   *JobRun    `json:"last_run,omitempty"`
+}
@@ -13,3 +13,48 @@ type Operation struct {
 	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
 	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
 }
+
+var OperationBalanceSign = map[string]int{
+	"deposit":      1,
+	"withdraw":     -1,
+	"transfer_in":  1,
+	"transfer_out": -1,
+	"reservation":  -1,
+	"refund":       1,
+	"expired":      1,
+	"revenue":      0,
+}
+
+func (o Operation) BalanceEffect() int {
+	return OperationBalanceSign[o.OperationType] * o.Amount
+}
+
+const (
+	OperationSortByDate   = "date"
+	OperationSortByAmount = "amount"
+)
+
+type OperationCursor struct {
+	CreatedAt time.Time
+	Amount    int
+	Id        int
+}
+
+type OperationFilter struct {
+	AccountId     int
+	OperationType string
+	ProductId     *int
+	From          *time.Time
+	To            *time.Time
+	MinAmount     *int
+	MaxAmount     *int
+	SortBy        string
+	Desc          bool
+	Limit         int
+	After         *OperationCursor
+}
+
+type OperationPage struct {
+	Operations []Operation `json:"operations"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
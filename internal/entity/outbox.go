@@ -0,0 +1,15 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type OutboxMessage struct {
+	Id        int64           `db:"id"`
+	EventType string          `db:"event_type"`
+	AccountId int             `db:"account_id"`
+	Payload   json.RawMessage `db:"payload"`
+	Attempts  int             `db:"attempts"`
+	CreatedAt time.Time       `db:"created_at"`
+}
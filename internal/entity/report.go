@@ -0,0 +1,57 @@
+package entity
+
+import "time"
+
+type RevenueReportItem struct {
+	ProductId   int    `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Revenue     int    `json:"revenue"`
+	Operations  int    `json:"operations"`
+}
+
+type RevenueReport struct {
+	Year            int                 `json:"year"`
+	Month           int                 `json:"month"`
+	Total           int                 `json:"total"`
+	Items           []RevenueReportItem `json:"items"`
+	LastOperationId int                 `json:"last_operation_id"`
+	GeneratedAt     time.Time           `json:"generated_at"`
+}
+
+type ReportStatus string
+
+const (
+	ReportPending   ReportStatus = "pending"
+	ReportCompleted ReportStatus = "completed"
+	ReportFailed    ReportStatus = "failed"
+)
+
+type ReportFile struct {
+	Id        int          `json:"id"`
+	Year      int          `json:"year"`
+	Month     int          `json:"month"`
+	Status    ReportStatus `json:"status"`
+	Checksum  *string      `json:"checksum,omitempty"`
+	Path      *string      `json:"path,omitempty"`
+	Error     *string      `json:"error,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt *time.Time   `json:"updated_at,omitempty"`
+}
+
+type ReportExportStatus string
+
+const (
+	ReportExportInProgress ReportExportStatus = "in_progress"
+	ReportExportCompleted  ReportExportStatus = "completed"
+)
+
+type ReportExport struct {
+	Year            int                `json:"year"`
+	Month           int                `json:"month"`
+	Status          ReportExportStatus `json:"status"`
+	LastOperationId int                `json:"last_operation_id"`
+	Revision        int                `json:"revision"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       *time.Time         `json:"updated_at,omitempty"`
+	CompletedAt     *time.Time         `json:"completed_at,omitempty"`
+}
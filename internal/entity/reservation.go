@@ -2,12 +2,36 @@ package entity
 
 import "time"
 
+type ReservationStatus string
+
+const (
+	ReservationPending   ReservationStatus = "pending"
+	ReservationConfirmed ReservationStatus = "confirmed"
+	ReservationRefunded  ReservationStatus = "refunded"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
 type Reservation struct {
-	Id        int        `db:"id"`
-	AccountId int        `db:"account_id"`
-	ProductId int        `db:"product_id"`
-	Amount    int        `db:"amount"`
-	CreatedAt time.Time  `db:"created_at"`
-	UpdatedAt *time.Time `db:"updated_at"` // Nullable field
-	DeletedAt *time.Time `db:"deleted_at"` // Nullable field
+	Id          int               `db:"id"`
+	AccountId   int               `db:"account_id"`
+	ProductId   int               `db:"product_id"`
+	OrderId     *int              `db:"order_id"` // Nullable field
+	Amount      int               `db:"amount"`
+	Remaining   int               `db:"remaining"`
+	Status      ReservationStatus `db:"status"`
+	CreatedAt   time.Time         `db:"created_at"`
+	UpdatedAt   *time.Time        `db:"updated_at"`   // Nullable field
+	ConfirmedAt *time.Time        `db:"confirmed_at"` // Nullable field
+	DeletedAt   *time.Time        `db:"deleted_at"`   // Nullable field
+}
+
+type OrderItem struct {
+	ProductId int `json:"product_id"`
+	Amount    int `json:"amount"`
+}
+
+type OrderReservation struct {
+	AccountId int         `json:"account_id"`
+	OrderId   int         `json:"order_id"`
+	Items     []OrderItem `json:"items"`
 }
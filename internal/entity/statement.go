@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+const (
+	StatementOpeningBalance = "opening_balance"
+	StatementOperation      = "operation"
+	StatementClosingBalance = "closing_balance"
+)
+
+type StatementEntry struct {
+	Type          string    `json:"type"`
+	OperationId   int       `json:"operation_id,omitempty"`
+	OperationType string    `json:"operation_type,omitempty"`
+	ProductId     *int      `json:"product_id,omitempty"`
+	Amount        int       `json:"amount"`
+	Balance       int       `json:"balance"`
+	Date          time.Time `json:"date"`
+}
@@ -0,0 +1,370 @@
+package migrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const lockKey = "user_balance:schema_migrations"
+
+var (
+	ErrChecksumMismatch  = errors.New("контрольная сумма применённой миграции не совпадает с файлом")
+	ErrMissingMigration  = errors.New("применённая миграция отсутствует среди файлов")
+	ErrNoDownMigration   = errors.New("для миграции нет down-файла")
+	ErrNoMigrationsTable = errors.New("таблица миграций schema_migrations отсутствует")
+)
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_\-]+?)(\.up|\.down)?\.sql$`)
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+
+	upChecksum string
+}
+
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Dirty     bool       `json:"checksum_mismatch,omitempty"`
+	Missing   bool       `json:"missing,omitempty"`
+}
+
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("некорректная версия миграции %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("версия %d используется разными миграциями: %s и %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == ".down" {
+			migration.Down = string(content)
+			continue
+		}
+		if migration.Up != "" {
+			return nil, fmt.Errorf("дублируется up-миграция версии %d", version)
+		}
+		migration.Up = string(content)
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("для миграции %d_%s нет up-файла", migration.Version, migration.Name)
+		}
+		migration.Checksum, migration.upChecksum = checksums(*migration)
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func checksums(migration Migration) (string, string) {
+	up := sha256.Sum256([]byte(migration.Up))
+
+	full := sha256.New()
+	full.Write([]byte(migration.Up))
+	full.Write([]byte{0})
+	full.Write([]byte(migration.Down))
+	return hex.EncodeToString(full.Sum(nil)), hex.EncodeToString(up[:])
+}
+
+func (m *Migrator) Up(ctx context.Context, dryRun bool) ([]Migration, error) {
+	var pending []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; !ok {
+				pending = append(pending, migration)
+			}
+		}
+
+		for _, migration := range pending {
+			if dryRun {
+				log.Printf("[dry-run] Будет применена миграция: %d_%s", migration.Version, migration.Name)
+				continue
+			}
+
+			log.Printf("Применение миграции: %d_%s", migration.Version, migration.Name)
+			started := time.Now()
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `
+					INSERT INTO schema_migrations (version, name, checksum, execution_ms)
+					VALUES ($1, $2, $3, $4)
+				`, migration.Version, migration.Name, migration.Checksum, time.Since(started).Milliseconds())
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("ошибка применения миграции %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("Миграция успешно применена: %d_%s", migration.Version, migration.Name)
+		}
+		return nil
+	})
+	return pending, err
+}
+
+func (m *Migrator) Down(ctx context.Context, steps int, dryRun bool) ([]Migration, error) {
+	var rolledBack []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("%w: %d_%s", ErrNoDownMigration, migration.Version, migration.Name)
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+
+		for _, migration := range rolledBack {
+			if dryRun {
+				log.Printf("[dry-run] Будет откачена миграция: %d_%s", migration.Version, migration.Name)
+				continue
+			}
+
+			log.Printf("Откат миграции: %d_%s", migration.Version, migration.Name)
+			err := inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("ошибка отката миграции %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("Миграция успешно откачена: %d_%s", migration.Version, migration.Name)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var exists bool
+	if err := m.db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("ошибка проверки таблицы schema_migrations: %w", err)
+	}
+
+	applied := map[int64]appliedMigration{}
+	if exists {
+		var err error
+		applied, err = loadApplied(ctx, m.db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var statuses []Status
+	known := make(map[int64]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Dirty = !migration.matches(record.Checksum)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for version, record := range applied {
+		if known[version] {
+			continue
+		}
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, Status{
+			Version:   version,
+			Name:      record.Name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	if !exists {
+		return statuses, ErrNoMigrationsTable
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) verify(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	applied, err := loadApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, record := range applied {
+		migration, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d_%s", ErrMissingMigration, version, record.Name)
+		}
+		if !migration.matches(record.Checksum) {
+			return nil, fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, version, migration.Name)
+		}
+		if record.Checksum != migration.Checksum {
+			_, err := conn.ExecContext(ctx, `UPDATE schema_migrations SET checksum = $2 WHERE version = $1`, version, migration.Checksum)
+			if err != nil {
+				return nil, fmt.Errorf("ошибка обновления контрольной суммы миграции %d_%s: %w", version, migration.Name, err)
+			}
+		}
+	}
+
+	return applied, nil
+}
+
+func (m Migration) matches(checksum string) bool {
+	return checksum == m.Checksum || checksum == m.upChecksum
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext($1))`, lockKey); err != nil {
+		return fmt.Errorf("ошибка получения блокировки миграций: %w", err)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock(hashtext($1))`, lockKey)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version      bigint primary key,
+			name         varchar(255) not null,
+			checksum     varchar(64)  not null,
+			execution_ms bigint       not null default 0,
+			applied_at   timestamp    not null default now()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("ошибка создания таблицы schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func loadApplied(ctx context.Context, q querier) (map[int64]appliedMigration, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var record appliedMigration
+		if err := rows.Scan(&record.Version, &record.Name, &record.Checksum, &record.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[record.Version] = record
+	}
+	return applied, rows.Err()
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,220 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        fstest.MapFS
+		wantVersions []int64
+		wantErr      bool
+	}{
+		{
+			name: "миграции сортируются по версии",
+			files: fstest.MapFS{
+				"0002_second.sql":      {Data: []byte("create table b ();")},
+				"0002_second.down.sql": {Data: []byte("drop table b;")},
+				"0001_first.up.sql":    {Data: []byte("create table a ();")},
+				"embed.go":             {Data: []byte("package migration")},
+				"README.md":            {Data: []byte("docs")},
+			},
+			wantVersions: []int64{1, 2},
+		},
+		{
+			name: "нет up-файла",
+			files: fstest.MapFS{
+				"0001_first.down.sql": {Data: []byte("drop table a;")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "одна версия у разных миграций",
+			files: fstest.MapFS{
+				"0001_first.sql":  {Data: []byte("create table a ();")},
+				"0001_second.sql": {Data: []byte("create table b ();")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "дублируется up-миграция",
+			files: fstest.MapFS{
+				"0001_first.sql":    {Data: []byte("create table a ();")},
+				"0001_first.up.sql": {Data: []byte("create table a ();")},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := load(tt.files)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ошибка %v, ожидалась ошибка: %v", err, tt.wantErr)
+			}
+			if len(migrations) != len(tt.wantVersions) {
+				t.Fatalf("загружено %d миграций, ожидалось %d", len(migrations), len(tt.wantVersions))
+			}
+			for i, migration := range migrations {
+				if migration.Version != tt.wantVersions[i] {
+					t.Errorf("позиция %d: версия %d, ожидалась %d", i, migration.Version, tt.wantVersions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	base := Migration{Up: "create table a ();", Down: "drop table a;"}
+	full, up := checksums(base)
+
+	tests := []struct {
+		name      string
+		migration Migration
+		wantSame  bool
+	}{
+		{name: "те же файлы", migration: base, wantSame: true},
+		{name: "изменён up-файл", migration: Migration{Up: "create table b ();", Down: base.Down}},
+		{name: "изменён down-файл", migration: Migration{Up: base.Up, Down: "drop table if exists a;"}},
+		{name: "удалён down-файл", migration: Migration{Up: base.Up}},
+		{name: "текст перенесён между файлами", migration: Migration{Up: base.Up + "drop", Down: " table a;"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := checksums(tt.migration)
+			if (got == full) != tt.wantSame {
+				t.Errorf("контрольные суммы совпадают: %v, ожидалось %v", got == full, tt.wantSame)
+			}
+		})
+	}
+
+	migration := base
+	migration.Checksum, migration.upChecksum = full, up
+	if !migration.matches(up) {
+		t.Error("контрольная сумма только up-файла должна приниматься для ранее применённых миграций")
+	}
+	if migration.matches("другая") {
+		t.Error("принята чужая контрольная сумма")
+	}
+}
+
+func openTestSchema(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN не задан, тест с базой данных пропущен")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("не удалось открыть соединение с БД: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+
+	schema := fmt.Sprintf("migrator_test_%d", time.Now().UnixNano())
+	if _, err := db.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("не удалось создать схему: %v", err)
+	}
+	if _, err := db.Exec(`SET search_path TO ` + schema); err != nil {
+		t.Fatalf("не удалось выбрать схему: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		db.Close()
+	})
+	return db
+}
+
+func TestMigratorLifecycle(t *testing.T) {
+	db := openTestSchema(t)
+	ctx := context.Background()
+	files := fstest.MapFS{
+		"0001_first.sql":       {Data: []byte("create table a (id int);")},
+		"0001_first.down.sql":  {Data: []byte("drop table a;")},
+		"0002_second.sql":      {Data: []byte("create table b (id int);")},
+		"0002_second.down.sql": {Data: []byte("drop table b;")},
+	}
+
+	m, err := New(db, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if !errors.Is(err, ErrNoMigrationsTable) {
+		t.Fatalf("ошибка %v, ожидалась %v", err, ErrNoMigrationsTable)
+	}
+	if len(statuses) != 2 || statuses[0].Applied || statuses[1].Applied {
+		t.Fatalf("статусы до применения %+v", statuses)
+	}
+	var exists bool
+	db.QueryRow(`SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if exists {
+		t.Fatal("status создал таблицу schema_migrations")
+	}
+
+	if applied, err := m.Up(ctx, true); err != nil || len(applied) != 2 {
+		t.Fatalf("dry-run: %d миграций, ошибка %v", len(applied), err)
+	}
+	if applied, err := m.Up(ctx, false); err != nil || len(applied) != 2 {
+		t.Fatalf("up: %d миграций, ошибка %v", len(applied), err)
+	}
+	if applied, err := m.Up(ctx, false); err != nil || len(applied) != 0 {
+		t.Fatalf("повторный up: %d миграций, ошибка %v", len(applied), err)
+	}
+
+	if _, err := db.Exec(`UPDATE schema_migrations SET checksum = $1 WHERE version = 1`, m.migrations[0].upChecksum); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err = m.Status(ctx)
+	if err != nil || statuses[0].Dirty {
+		t.Fatalf("миграция с контрольной суммой только up-файла помечена изменённой: %+v, %v", statuses, err)
+	}
+	if _, err := m.Up(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	var checksum string
+	db.QueryRow(`SELECT checksum FROM schema_migrations WHERE version = 1`).Scan(&checksum)
+	if checksum != m.migrations[0].Checksum {
+		t.Errorf("контрольная сумма не обновлена: %s", checksum)
+	}
+
+	changed := fstest.MapFS{}
+	for name, file := range files {
+		changed[name] = file
+	}
+	changed["0002_second.down.sql"] = &fstest.MapFile{Data: []byte("drop table if exists b;")}
+	dirty, err := New(db, changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err = dirty.Status(ctx)
+	if err != nil || !statuses[1].Dirty {
+		t.Fatalf("изменённый down-файл не обнаружен: %+v, %v", statuses, err)
+	}
+	if _, err := dirty.Up(ctx, false); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("ошибка %v, ожидалась %v", err, ErrChecksumMismatch)
+	}
+
+	rolledBack, err := m.Down(ctx, 1, false)
+	if err != nil || len(rolledBack) != 1 || rolledBack[0].Version != 2 {
+		t.Fatalf("down: %+v, ошибка %v", rolledBack, err)
+	}
+	statuses, err = m.Status(ctx)
+	if err != nil || !statuses[0].Applied || statuses[1].Applied {
+		t.Fatalf("статусы после отката %+v, %v", statuses, err)
+	}
+}
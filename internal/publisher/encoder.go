@@ -0,0 +1,44 @@
+package publisher
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"user_balance/pkg/event"
+)
+
+type Encoder interface {
+	Encode(envelope event.Envelope) ([]byte, error)
+	ContentType() string
+}
+
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(envelope event.Envelope) ([]byte, error) {
+	return json.Marshal(envelope)
+}
+
+func (JSONEncoder) ContentType() string {
+	return "application/cloudevents+json"
+}
+
+type ProtobufEncoder struct{}
+
+func (ProtobufEncoder) Encode(envelope event.Envelope) ([]byte, error) {
+	return event.MarshalProtobuf(envelope)
+}
+
+func (ProtobufEncoder) ContentType() string {
+	return event.ProtobufContentType
+}
+
+func NewEncoder(encoding string) (Encoder, error) {
+	switch encoding {
+	case "", "json":
+		return JSONEncoder{}, nil
+	case "protobuf":
+		return ProtobufEncoder{}, nil
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат кодирования событий: %s", encoding)
+	}
+}
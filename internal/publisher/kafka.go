@@ -0,0 +1,65 @@
+package publisher
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+
+	"user_balance/pkg/event"
+
+	"github.com/IBM/sarama"
+)
+
+type KafkaPublisher struct {
+	producer sarama.SyncProducer
+	encoder  Encoder
+}
+
+func NewKafkaPublisher(brokers []string, config *sarama.Config, encoder Encoder) (*KafkaPublisher, error) {
+	log.Printf("Инициализация Kafka producer. Используем брокеры: %s", strings.Join(brokers, ","))
+
+	producer, err := sarama.NewSyncProducer(brokers, config)
+	if err != nil {
+		log.Printf("Ошибка подключения к Kafka. Брокеры: %s, Ошибка: %v", brokers, err)
+		return nil, err
+	}
+
+	log.Printf("Kafka producer успешно инициализирован. Подключен к брокерам: %s", brokers)
+	return &KafkaPublisher{producer: producer, encoder: encoder}, nil
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, topic string, envelope event.Envelope) error {
+	value, err := p.encoder.Encode(envelope)
+	if err != nil {
+		return err
+	}
+
+	producerMessage := &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(value),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte("ce_id"), Value: []byte(envelope.Id)},
+			{Key: []byte("ce_type"), Value: []byte(envelope.Type)},
+			{Key: []byte("ce_source"), Value: []byte(envelope.Source)},
+			{Key: []byte("ce_specversion"), Value: []byte(envelope.SpecVersion)},
+			{Key: []byte("ce_schemaversion"), Value: []byte(strconv.Itoa(envelope.SchemaVersion))},
+			{Key: []byte("content-type"), Value: []byte(p.encoder.ContentType())},
+		},
+	}
+	if envelope.PartitionKey != "" {
+		producerMessage.Key = sarama.StringEncoder(envelope.PartitionKey)
+	}
+
+	_, _, err = p.producer.SendMessage(producerMessage)
+	return err
+}
+
+func (p *KafkaPublisher) Close() error {
+	if err := p.producer.Close(); err != nil {
+		log.Printf("Ошибка закрытия Kafka producer: %v", err)
+		return err
+	}
+	log.Println("Kafka producer закрыт")
+	return nil
+}
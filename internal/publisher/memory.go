@@ -0,0 +1,43 @@
+package publisher
+
+import (
+	"context"
+	"sync"
+
+	"user_balance/pkg/event"
+)
+
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, topic string, envelope event.Envelope) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, newMessage(topic, envelope))
+	return nil
+}
+
+func (p *MemoryPublisher) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	messages := make([]Message, len(p.messages))
+	copy(messages, p.messages)
+	return messages
+}
+
+func (p *MemoryPublisher) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = nil
+}
+
+func (p *MemoryPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,29 @@
+package publisher
+
+import (
+	"context"
+	"time"
+
+	"user_balance/pkg/event"
+)
+
+type EventPublisher interface {
+	Publish(ctx context.Context, topic string, envelope event.Envelope) error
+	Close() error
+}
+
+type Message struct {
+	Topic       string         `json:"topic"`
+	Key         string         `json:"key,omitempty"`
+	Event       event.Envelope `json:"event"`
+	PublishedAt time.Time      `json:"published_at"`
+}
+
+func newMessage(topic string, envelope event.Envelope) Message {
+	return Message{
+		Topic:       topic,
+		Key:         envelope.PartitionKey,
+		Event:       envelope,
+		PublishedAt: time.Now().UTC(),
+	}
+}
@@ -29,7 +29,7 @@ func (r *AccountRepo) CreateAccount(ctx context.Context) (int, error) {
 func (r *AccountRepo) GetAccount(ctx context.Context, id int) (entity.Account, error) {
 	var account entity.Account
 	query := `
-		SELECT id, balance, created_at, updated_at, deleted_at
+		SELECT id, balance, held, created_at, updated_at, deleted_at
 		FROM accounts
 		WHERE id = $1
 	`
@@ -37,6 +37,7 @@ func (r *AccountRepo) GetAccount(ctx context.Context, id int) (entity.Account, e
 	err := r.pg.QueryRowContext(ctx, query, id).Scan(
 		&account.Id,
 		&account.Balance,
+		&account.Held,
 		&account.CreatedAt,
 		&account.UpdatedAt,
 		&account.DeletedAt,
@@ -77,14 +78,20 @@ func (r *AccountRepo) Deposit(ctx context.Context, id, amount int) (int, int, er
 	}
 
 	if deletedCheck != nil {
+		tx.Rollback()
 		return 0, 0, repoerrs.ErrDataDeleted
 	}
 
-	queryInsertOperation := `
-		INSERT INTO operations (account_id, amount, operation_type, created_at)
-		VALUES ($1, $2, $3, NOW())
-	`
-	_, err = tx.ExecContext(ctx, queryInsertOperation, id, amount, "deposit")
+	err = insertOperation(ctx, tx, id, amount, "deposit", nil)
+	if err != nil {
+		tx.Rollback()
+		return 0, 0, err
+	}
+
+	err = postLedgerTransaction(ctx, tx, "deposit", nil,
+		systemPosting(ledgerExternalCash, -amount),
+		customerPosting(id, amount),
+	)
 	if err != nil {
 		tx.Rollback()
 		return 0, 0, err
@@ -105,7 +112,7 @@ func (r *AccountRepo) Withdraw(ctx context.Context, id, amount int) (int, int, e
 	}
 
 	queryGetBalance := `
-	SELECT balance, deleted_at FROM accounts WHERE id=$1
+	SELECT balance, deleted_at FROM accounts WHERE id=$1 FOR UPDATE
 	`
 
 	var balance int
@@ -140,12 +147,16 @@ func (r *AccountRepo) Withdraw(ctx context.Context, id, amount int) (int, int, e
 		return 0, 0, err
 	}
 
-	queryInsertOperation := `
-		INSERT INTO operations (account_id, amount, operation_type, created_at)
-		VALUES ($1, $2, $3, NOW())
-	`
+	err = insertOperation(ctx, tx, id, amount, "withdraw", nil)
+	if err != nil {
+		tx.Rollback()
+		return 0, 0, err
+	}
 
-	_, err = tx.ExecContext(ctx, queryInsertOperation, id, amount, "withdraw")
+	err = postLedgerTransaction(ctx, tx, "withdraw", nil,
+		customerPosting(id, -amount),
+		systemPosting(ledgerExternalCash, amount),
+	)
 	if err != nil {
 		tx.Rollback()
 		return 0, 0, err
@@ -165,36 +176,32 @@ func (r *AccountRepo) Transfer(ctx context.Context, fromID, toID, amount int) (i
 		return 0, 0, err
 	}
 
-	queryGetBalance := `
-    SELECT balance, deleted_at FROM accounts WHERE id=$1
+	queryLockAccount := `
+    SELECT balance, deleted_at FROM accounts WHERE id=$1 FOR UPDATE
     `
 
-	var fromBalance int
-	var fromDeletedAt *time.Time
-	err = tx.QueryRowContext(ctx, queryGetBalance, fromID).Scan(&fromBalance, &fromDeletedAt)
-	if err != nil {
-		tx.Rollback()
-		return 0, 0, err
-	}
-	if fromDeletedAt != nil {
-		tx.Rollback()
-		return 0, 0, repoerrs.ErrNotEnoughBalance
-	}
-
-	var toBalance int
-	var toDeletedAt *time.Time
-	err = tx.QueryRowContext(ctx, queryGetBalance, toID).Scan(&toBalance, &toDeletedAt)
-	if err != nil {
-		tx.Rollback()
-		return 0, 0, err
+	firstID, secondID := fromID, toID
+	if firstID > secondID {
+		firstID, secondID = secondID, firstID
 	}
 
-	if toDeletedAt != nil {
-		tx.Rollback()
-		return 0, 0, repoerrs.ErrNotEnoughBalance
+	balances := make(map[int]int, 2)
+	for _, id := range []int{firstID, secondID} {
+		var balance int
+		var deletedAt *time.Time
+		err = tx.QueryRowContext(ctx, queryLockAccount, id).Scan(&balance, &deletedAt)
+		if err != nil {
+			tx.Rollback()
+			return 0, 0, err
+		}
+		if deletedAt != nil {
+			tx.Rollback()
+			return 0, 0, repoerrs.ErrNotEnoughBalance
+		}
+		balances[id] = balance
 	}
 
-	if fromBalance < amount {
+	if balances[fromID] < amount {
 		tx.Rollback()
 		return 0, 0, repoerrs.ErrNotEnoughBalance
 	}
@@ -227,23 +234,22 @@ func (r *AccountRepo) Transfer(ctx context.Context, fromID, toID, amount int) (i
 		return 0, 0, err
 	}
 
-	queryInsertFromOperation := `
-    INSERT INTO operations (account_id, amount, operation_type, created_at)
-    VALUES ($1, $2, $3, NOW())
-    `
-
-	_, err = tx.ExecContext(ctx, queryInsertFromOperation, fromID, amount, "transfer_out")
+	err = insertOperation(ctx, tx, fromID, amount, "transfer_out", nil)
 	if err != nil {
 		tx.Rollback()
 		return 0, 0, err
 	}
 
-	queryInsertToOperation := `
-    INSERT INTO operations (account_id, amount, operation_type, created_at)
-    VALUES ($1, $2, $3, NOW())
-    `
+	err = insertOperation(ctx, tx, toID, amount, "transfer_in", nil)
+	if err != nil {
+		tx.Rollback()
+		return 0, 0, err
+	}
 
-	_, err = tx.ExecContext(ctx, queryInsertToOperation, toID, amount, "transfer_in")
+	err = postLedgerTransaction(ctx, tx, "transfer", nil,
+		customerPosting(fromID, -amount),
+		customerPosting(toID, amount),
+	)
 	if err != nil {
 		tx.Rollback()
 		return 0, 0, err
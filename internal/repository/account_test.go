@@ -0,0 +1,126 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"user_balance/internal/migrator"
+	"user_balance/internal/repository"
+	"user_balance/internal/repository/repoerrs"
+	"user_balance/migration"
+
+	_ "github.com/lib/pq"
+)
+
+const (
+	stressAccounts       = 4
+	stressInitialBalance = 1000
+	stressWorkers        = 32
+	stressIterations     = 50
+	stressAmount         = 70
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN не задан, тест с базой данных пропущен")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("не удалось открыть соединение с БД: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m, err := migrator.New(db, migration.FS)
+	if err != nil {
+		t.Fatalf("не удалось загрузить миграции: %v", err)
+	}
+	if _, err := m.Up(context.Background(), false); err != nil {
+		t.Fatalf("не удалось применить миграции: %v", err)
+	}
+	return db
+}
+
+func TestConcurrentWithdrawAndTransferKeepBalancesNonNegative(t *testing.T) {
+	db := openTestDB(t)
+	repo := repository.NewAccountRepo(db)
+	ctx := context.Background()
+
+	ids := make([]int, stressAccounts)
+	for i := range ids {
+		id, err := repo.CreateAccount(ctx)
+		if err != nil {
+			t.Fatalf("не удалось создать аккаунт: %v", err)
+		}
+		if _, _, err := repo.Deposit(ctx, id, stressInitialBalance); err != nil {
+			t.Fatalf("не удалось пополнить аккаунт %d: %v", id, err)
+		}
+		ids[i] = id
+	}
+
+	var withdrawn, rejected atomic.Int64
+	errs := make(chan error, stressWorkers*stressIterations)
+
+	var wg sync.WaitGroup
+	for w := 0; w < stressWorkers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < stressIterations; i++ {
+				from := ids[(worker+i)%len(ids)]
+				to := ids[(worker+i+1)%len(ids)]
+
+				var err error
+				if (worker+i)%2 == 0 {
+					_, _, err = repo.Withdraw(ctx, from, stressAmount)
+					if err == nil {
+						withdrawn.Add(stressAmount)
+					}
+				} else {
+					_, _, err = repo.Transfer(ctx, from, to, stressAmount)
+				}
+
+				switch {
+				case err == nil:
+				case errors.Is(err, repoerrs.ErrNotEnoughBalance):
+					rejected.Add(1)
+				default:
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("неожиданная ошибка операции: %v", err)
+	}
+
+	total := 0
+	for _, id := range ids {
+		account, err := repo.GetAccount(ctx, id)
+		if err != nil {
+			t.Fatalf("не удалось получить аккаунт %d: %v", id, err)
+		}
+		if account.Balance < 0 {
+			t.Errorf("баланс аккаунта %d отрицательный: %d", id, account.Balance)
+		}
+		total += account.Balance
+	}
+
+	expected := stressAccounts*stressInitialBalance - int(withdrawn.Load())
+	if total != expected {
+		t.Errorf("суммарный баланс %d, ожидался %d", total, expected)
+	}
+	if rejected.Load() == 0 {
+		t.Errorf("ни одна операция не была отклонена из-за нехватки средств, нагрузка недостаточна")
+	}
+}
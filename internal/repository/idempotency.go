@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"user_balance/internal/entity"
+)
+
+type IdempotencyRepo struct {
+	pg *sql.DB
+}
+
+func NewIdempotencyRepo(pg *sql.DB) *IdempotencyRepo {
+	return &IdempotencyRepo{pg}
+}
+
+func (r *IdempotencyRepo) LockIdempotencyKey(ctx context.Context, key, requestHash string, lease, ttl time.Duration) (entity.IdempotencyRecord, bool, error) {
+	queryInsertKey := `
+		INSERT INTO idempotency_keys (key, request_hash, locked_until, expires_at)
+		VALUES ($1, $2, NOW() + $3 * INTERVAL '1 millisecond', NOW() + $4 * INTERVAL '1 millisecond')
+		ON CONFLICT (key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			content_type = '',
+			response = NULL,
+			completed_at = NULL,
+			locked_until = EXCLUDED.locked_until,
+			expires_at = EXCLUDED.expires_at,
+			created_at = NOW()
+		WHERE idempotency_keys.expires_at < NOW()
+			OR (idempotency_keys.completed_at IS NULL
+				AND idempotency_keys.request_hash = EXCLUDED.request_hash
+				AND (idempotency_keys.locked_until IS NULL OR idempotency_keys.locked_until < NOW()))
+		RETURNING key, request_hash, created_at, locked_until
+	`
+
+	var record entity.IdempotencyRecord
+	err := r.pg.QueryRowContext(ctx, queryInsertKey, key, requestHash, lease.Milliseconds(), ttl.Milliseconds()).Scan(
+		&record.Key,
+		&record.RequestHash,
+		&record.CreatedAt,
+		&record.LockedUntil,
+	)
+	if err == nil {
+		return record, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return entity.IdempotencyRecord{}, false, err
+	}
+
+	queryGetKey := `
+		SELECT key, request_hash, status_code, content_type, response, created_at, completed_at, locked_until
+		FROM idempotency_keys
+		WHERE key = $1
+	`
+	err = r.pg.QueryRowContext(ctx, queryGetKey, key).Scan(
+		&record.Key,
+		&record.RequestHash,
+		&record.StatusCode,
+		&record.ContentType,
+		&record.Response,
+		&record.CreatedAt,
+		&record.CompletedAt,
+		&record.LockedUntil,
+	)
+	if err != nil {
+		return entity.IdempotencyRecord{}, false, err
+	}
+
+	return record, false, nil
+}
+
+func (r *IdempotencyRepo) SaveIdempotentResponse(ctx context.Context, key string, statusCode int, contentType string, response []byte, ttl time.Duration) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $2, content_type = $3, response = $4, completed_at = NOW(), locked_until = NULL,
+			expires_at = NOW() + $5 * INTERVAL '1 millisecond'
+		WHERE key = $1
+	`
+	_, err := r.pg.ExecContext(ctx, query, key, statusCode, contentType, response, ttl.Milliseconds())
+	return err
+}
+
+func (r *IdempotencyRepo) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	query := "DELETE FROM idempotency_keys WHERE key = $1 AND completed_at IS NULL"
+	_, err := r.pg.ExecContext(ctx, query, key)
+	return err
+}
+
+func (r *IdempotencyRepo) DeleteExpiredIdempotencyKeys(ctx context.Context) (int, error) {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE expires_at < NOW()
+			AND (locked_until IS NULL OR locked_until < NOW())
+	`
+	result, err := r.pg.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
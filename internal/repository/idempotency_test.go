@@ -0,0 +1,65 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	"user_balance/internal/repository"
+)
+
+func TestIdempotencyKeyLifecycle(t *testing.T) {
+	db := openTestDB(t)
+	repo := repository.NewIdempotencyRepo(db)
+	ctx := context.Background()
+	key := fmt.Sprintf("test:%d", time.Now().UnixNano())
+
+	if _, created, err := repo.LockIdempotencyKey(ctx, key, "h1", time.Minute, time.Hour); err != nil || !created {
+		t.Fatalf("первая блокировка: created=%v, err=%v", created, err)
+	}
+
+	steps := []struct {
+		name        string
+		hash        string
+		wantCreated bool
+		wantStatus  bool
+	}{
+		{name: "повтор во время выполнения", hash: "h1"},
+		{name: "другой запрос во время выполнения", hash: "h2"},
+	}
+	for _, step := range steps {
+		record, created, err := repo.LockIdempotencyKey(ctx, key, step.hash, time.Minute, time.Hour)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if created != step.wantCreated || (record.StatusCode != nil) != step.wantStatus {
+			t.Errorf("%s: created=%v, status=%v", step.name, created, record.StatusCode)
+		}
+	}
+
+	if err := repo.SaveIdempotentResponse(ctx, key, 200, "application/json", []byte(`{}`), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	record, created, err := repo.LockIdempotencyKey(ctx, key, "h1", time.Minute, time.Hour)
+	if err != nil || created || record.StatusCode == nil || *record.StatusCode != 200 {
+		t.Fatalf("повтор после завершения: created=%v, record=%+v, err=%v", created, record, err)
+	}
+
+	if err := repo.SaveIdempotentResponse(ctx, key, 200, "application/json", []byte(`{}`), -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, created, err := repo.LockIdempotencyKey(ctx, key, "h2", time.Minute, -time.Second); err != nil || !created {
+		t.Fatalf("просроченный ключ не переиспользован: created=%v, err=%v", created, err)
+	}
+	if err := repo.SaveIdempotentResponse(ctx, key, 201, "application/json", []byte(`{}`), -time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := repo.DeleteExpiredIdempotencyKeys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted == 0 {
+		t.Error("просроченный ключ не удалён")
+	}
+}
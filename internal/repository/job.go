@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+	"user_balance/internal/entity"
+)
+
+type JobRepo struct {
+	pg *sql.DB
+}
+
+func NewJobRepo(pg *sql.DB) *JobRepo {
+	return &JobRepo{pg}
+}
+
+func (r *JobRepo) LockJob(ctx context.Context, name string) (func(), bool, error) {
+	conn, err := r.pg.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext('job:' || $1))`, name).Scan(&locked)
+	if err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if !locked {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext('job:' || $1))`, name)
+		conn.Close()
+	}
+	return unlock, true, nil
+}
+
+func (r *JobRepo) StartJobRun(ctx context.Context, name string, trigger entity.JobTrigger, scheduledAt time.Time, instance string, args entity.JobArgs) (int64, bool, error) {
+	var parameters []byte
+	if len(args) > 0 {
+		var err error
+		parameters, err = json.Marshal(args)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	query := `
+		INSERT INTO job_runs (job_name, trigger, scheduled_at, instance, status, parameters)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (job_name, scheduled_at) DO NOTHING
+		RETURNING id
+	`
+	var id int64
+	err := r.pg.QueryRowContext(ctx, query, name, trigger, scheduledAt, instance, entity.JobRunRunning, parameters).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return id, true, nil
+}
+
+func (r *JobRepo) FinishJobRun(ctx context.Context, id int64, status entity.JobRunStatus, rowsProcessed int, reason *string) error {
+	query := `
+		UPDATE job_runs
+		SET status = $2, rows_processed = $3, error = $4, finished_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.pg.ExecContext(ctx, query, id, status, rowsProcessed, reason)
+	return err
+}
+
+func (r *JobRepo) GetJobRun(ctx context.Context, id int64) (entity.JobRun, error) {
+	query := `
+		SELECT id, job_name, trigger, scheduled_at, parameters, instance, status, rows_processed, error, started_at, finished_at
+		FROM job_runs
+		WHERE id = $1
+	`
+	return scanJobRun(r.pg.QueryRowContext(ctx, query, id))
+}
+
+func (r *JobRepo) ListJobRuns(ctx context.Context, name string, limit int) ([]entity.JobRun, error) {
+	query := `
+		SELECT id, job_name, trigger, scheduled_at, parameters, instance, status, rows_processed, error, started_at, finished_at
+		FROM job_runs
+		WHERE job_name = $1
+		ORDER BY started_at DESC, id DESC
+		LIMIT $2
+	`
+	rows, err := r.pg.QueryContext(ctx, query, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []entity.JobRun
+	for rows.Next() {
+		run, err := scanJobRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+func (r *JobRepo) FailStaleJobRuns(ctx context.Context, name, reason string) (int, error) {
+	query := `
+		UPDATE job_runs
+		SET status = $3, error = $4, finished_at = NOW()
+		WHERE job_name = $1 AND status = $2
+	`
+	result, err := r.pg.ExecContext(ctx, query, name, entity.JobRunRunning, entity.JobRunFailed, reason)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+func (r *JobRepo) DeleteJobRunsBefore(ctx context.Context, before time.Time) (int, error) {
+	query := `
+		DELETE FROM job_runs
+		WHERE started_at < $1
+			AND status <> $2
+			AND id NOT IN (SELECT MAX(id) FROM job_runs GROUP BY job_name)
+	`
+	result, err := r.pg.ExecContext(ctx, query, before, entity.JobRunRunning)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJobRun(row rowScanner) (entity.JobRun, error) {
+	var run entity.JobRun
+	var parameters []byte
+	err := row.Scan(
+		&run.Id,
+		&run.JobName,
+		&run.Trigger,
+		&run.ScheduledAt,
+		&parameters,
+		&run.Instance,
+		&run.Status,
+		&run.RowsProcessed,
+		&run.Error,
+		&run.StartedAt,
+		&run.FinishedAt,
+	)
+	if err != nil {
+		return entity.JobRun{}, err
+	}
+
+	if len(parameters) > 0 {
+		if err := json.Unmarshal(parameters, &run.Parameters); err != nil {
+			return entity.JobRun{}, err
+		}
+	}
+	return run, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"user_balance/internal/repository/repoerrs"
+)
+
+const (
+	ledgerExternalCash     = "external_cash"
+	ledgerRevenue          = "revenue"
+	ledgerReservationHolds = "reservation_holds"
+)
+
+type posting struct {
+	accountID int
+	code      string
+	amount    int
+}
+
+func customerPosting(accountID, amount int) posting {
+	return posting{accountID: accountID, amount: amount}
+}
+
+func systemPosting(code string, amount int) posting {
+	return posting{code: code, amount: amount}
+}
+
+func postLedgerTransaction(ctx context.Context, tx *sql.Tx, txType string, reservationID *int, postings ...posting) error {
+	sum := 0
+	for _, p := range postings {
+		sum += p.amount
+	}
+	if sum != 0 {
+		return fmt.Errorf("%w: %s, сумма проводок %d", repoerrs.ErrUnbalancedTransaction, txType, sum)
+	}
+
+	var transactionID int
+	queryInsertTransaction := `
+		INSERT INTO ledger_transactions (type, reservation_id)
+		VALUES ($1, $2)
+		RETURNING id
+	`
+	err := tx.QueryRowContext(ctx, queryInsertTransaction, txType, reservationID).Scan(&transactionID)
+	if err != nil {
+		return err
+	}
+
+	queryInsertPosting := `
+		INSERT INTO ledger_postings (transaction_id, ledger_account_id, amount)
+		VALUES ($1, $2, $3)
+	`
+	for _, p := range postings {
+		if p.amount == 0 {
+			continue
+		}
+
+		ledgerAccountID, err := resolveLedgerAccount(ctx, tx, p)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, queryInsertPosting, transactionID, ledgerAccountID, p.amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resolveLedgerAccount(ctx context.Context, tx *sql.Tx, p posting) (int, error) {
+	var id int
+	if p.code != "" {
+		query := "SELECT id FROM ledger_accounts WHERE code = $1"
+		err := tx.QueryRowContext(ctx, query, p.code).Scan(&id)
+		return id, err
+	}
+
+	queryInsertAccount := `
+		INSERT INTO ledger_accounts (code, account_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+	_, err := tx.ExecContext(ctx, queryInsertAccount, fmt.Sprintf("account:%d", p.accountID), p.accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	query := "SELECT id FROM ledger_accounts WHERE account_id = $1"
+	err = tx.QueryRowContext(ctx, query, p.accountID).Scan(&id)
+	return id, err
+}
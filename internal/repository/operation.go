@@ -3,7 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"log"
+	"sort"
+	"strings"
 	"time"
 
 	"user_balance/internal/entity"
@@ -60,3 +63,206 @@ func (r *OperationRepo) GetMonthlyOperations(ctx context.Context, startDate, end
 	}
 	return operations, nil
 }
+
+func (r *OperationRepo) GetAccountOperations(ctx context.Context, filter entity.OperationFilter) ([]entity.Operation, error) {
+	query := `
+		SELECT
+			id, account_id, amount, operation_type, product_id, description, created_at, updated_at, deleted_at
+		FROM operations
+		WHERE account_id = $1 AND deleted_at IS NULL
+	`
+	args := []any{filter.AccountId}
+	where := func(condition string, value any) {
+		args = append(args, value)
+		query += fmt.Sprintf(" AND "+condition, len(args))
+	}
+
+	if filter.OperationType != "" {
+		where("operation_type = $%d", filter.OperationType)
+	}
+	if filter.ProductId != nil {
+		where("product_id = $%d", *filter.ProductId)
+	}
+	if filter.From != nil {
+		where("created_at >= $%d", filter.From.UTC())
+	}
+	if filter.To != nil {
+		where("created_at < $%d", filter.To.UTC())
+	}
+	if filter.MinAmount != nil {
+		where("amount >= $%d", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		where("amount <= $%d", *filter.MaxAmount)
+	}
+
+	column := "created_at"
+	if filter.SortBy == entity.OperationSortByAmount {
+		column = "amount"
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if filter.After != nil {
+		var value any = filter.After.CreatedAt.UTC()
+		if column == "amount" {
+			value = filter.After.Amount
+		}
+		args = append(args, value, filter.After.Id)
+		query += fmt.Sprintf(" AND (%s, id) %s ($%d, $%d)", column, comparison, len(args)-1, len(args))
+	}
+
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT $%d", column, direction, direction, len(args))
+
+	rows, err := r.pg.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var operations []entity.Operation
+	for rows.Next() {
+		var op entity.Operation
+		err := rows.Scan(
+			&op.Id,
+			&op.AccountId,
+			&op.Amount,
+			&op.OperationType,
+			&op.ProductId,
+			&op.Description,
+			&op.CreatedAt,
+			&op.UpdatedAt,
+			&op.DeletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, op)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return operations, nil
+}
+
+func (r *OperationRepo) GetBalanceBefore(ctx context.Context, accountID int, before time.Time) (int, error) {
+	query := fmt.Sprintf(`
+		SELECT COALESCE(SUM(%s), 0)
+		FROM operations
+		WHERE account_id = $1 AND created_at < $2 AND deleted_at IS NULL
+	`, balanceEffectSQL())
+
+	var balance int
+	err := r.pg.QueryRowContext(ctx, query, accountID, before.UTC()).Scan(&balance)
+	if err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+func (r *OperationRepo) StreamAccountOperations(ctx context.Context, accountID int, from, to time.Time, fn func(entity.Operation) error) error {
+	query := `
+		SELECT
+			id, account_id, amount, operation_type, product_id, description, created_at, updated_at, deleted_at
+		FROM operations
+		WHERE account_id = $1 AND created_at >= $2 AND created_at < $3 AND deleted_at IS NULL
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.pg.QueryContext(ctx, query, accountID, from.UTC(), to.UTC())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var op entity.Operation
+		err := rows.Scan(
+			&op.Id,
+			&op.AccountId,
+			&op.Amount,
+			&op.OperationType,
+			&op.ProductId,
+			&op.Description,
+			&op.CreatedAt,
+			&op.UpdatedAt,
+			&op.DeletedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if err := fn(op); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *OperationRepo) GetRevenueByProduct(ctx context.Context, startDate, endDate time.Time) ([]entity.RevenueReportItem, error) {
+	query := `
+		SELECT o.product_id, p.name, SUM(o.amount), COUNT(*)
+		FROM operations o
+		JOIN products p ON p.id = o.product_id
+		WHERE o.operation_type = 'revenue'
+			AND o.created_at >= $1 AND o.created_at < $2
+			AND o.deleted_at IS NULL
+		GROUP BY o.product_id, p.name
+		ORDER BY o.product_id
+	`
+
+	rows, err := r.pg.QueryContext(ctx, query, startDate.UTC(), endDate.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []entity.RevenueReportItem
+	for rows.Next() {
+		var item entity.RevenueReportItem
+		err := rows.Scan(&item.ProductId, &item.ProductName, &item.Revenue, &item.Operations)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *OperationRepo) GetRevenueWatermark(ctx context.Context, startDate, endDate time.Time) (int, error) {
+	query := `
+		SELECT COALESCE(MAX(id), 0)
+		FROM operations
+		WHERE operation_type = 'revenue'
+			AND created_at >= $1 AND created_at < $2
+			AND deleted_at IS NULL
+	`
+
+	var lastID int
+	if err := r.pg.QueryRowContext(ctx, query, startDate.UTC(), endDate.UTC()).Scan(&lastID); err != nil {
+		return 0, err
+	}
+	return lastID, nil
+}
+
+func balanceEffectSQL() string {
+	types := make([]string, 0, len(entity.OperationBalanceSign))
+	for operationType := range entity.OperationBalanceSign {
+		types = append(types, operationType)
+	}
+	sort.Strings(types)
+
+	var b strings.Builder
+	b.WriteString("CASE operation_type")
+	for _, operationType := range types {
+		fmt.Fprintf(&b, " WHEN '%s' THEN %d * amount", operationType, entity.OperationBalanceSign[operationType])
+	}
+	b.WriteString(" ELSE 0 END")
+	return b.String()
+}
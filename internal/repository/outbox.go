@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+	"user_balance/internal/entity"
+	"user_balance/pkg/event"
+)
+
+const outboxMaxBackoffSeconds = 300
+
+type OutboxRepo struct {
+	pg *sql.DB
+}
+
+func NewOutboxRepo(pg *sql.DB) *OutboxRepo {
+	return &OutboxRepo{pg}
+}
+
+func (r *OutboxRepo) ProcessOutbox(ctx context.Context, limit int, lease time.Duration, publish func(entity.OutboxMessage) error) (int, error) {
+	messages, err := r.claimOutbox(ctx, limit, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	queryMarkSent := `
+		UPDATE outbox
+		SET sent_at = NOW(), attempts = attempts + 1, last_error = NULL, locked_until = NULL
+		WHERE id = $1
+	`
+	queryMarkFailed := `
+		UPDATE outbox
+		SET attempts = attempts + 1,
+			last_error = $2,
+			locked_until = NULL,
+			next_attempt_at = NOW() + make_interval(secs => LEAST(POWER(2, attempts), $3))
+		WHERE id = $1
+	`
+	queryRelease := `
+		UPDATE outbox
+		SET locked_until = NULL
+		WHERE id = $1
+	`
+
+	sent := 0
+	failedAccounts := make(map[int]bool)
+	for _, message := range messages {
+		if failedAccounts[message.AccountId] {
+			_, err = r.pg.ExecContext(ctx, queryRelease, message.Id)
+		} else if publishErr := publish(message); publishErr != nil {
+			failedAccounts[message.AccountId] = true
+			_, err = r.pg.ExecContext(ctx, queryMarkFailed, message.Id, publishErr.Error(), outboxMaxBackoffSeconds)
+		} else {
+			_, err = r.pg.ExecContext(ctx, queryMarkSent, message.Id)
+			sent++
+		}
+		if err != nil {
+			return sent, err
+		}
+	}
+
+	return sent, nil
+}
+
+func (r *OutboxRepo) claimOutbox(ctx context.Context, limit int, lease time.Duration) ([]entity.OutboxMessage, error) {
+	tx, err := r.pg.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('user_balance:outbox'))`); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	queryClaimPending := `
+		WITH claimed AS (
+			SELECT o.id
+			FROM outbox o
+			WHERE o.sent_at IS NULL
+				AND o.next_attempt_at <= NOW()
+				AND (o.locked_until IS NULL OR o.locked_until < NOW())
+				AND NOT EXISTS (
+					SELECT 1
+					FROM outbox prior
+					WHERE prior.account_id = o.account_id
+						AND prior.sent_at IS NULL
+						AND prior.id < o.id
+						AND (prior.next_attempt_at > NOW() OR prior.locked_until >= NOW())
+				)
+			ORDER BY o.id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE outbox
+		SET locked_until = NOW() + $2 * INTERVAL '1 millisecond'
+		FROM claimed
+		WHERE outbox.id = claimed.id
+		RETURNING outbox.id, outbox.event_type, outbox.account_id, outbox.payload, outbox.attempts, outbox.created_at
+	`
+	rows, err := tx.QueryContext(ctx, queryClaimPending, limit, lease.Milliseconds())
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var messages []entity.OutboxMessage
+	for rows.Next() {
+		var message entity.OutboxMessage
+		err := rows.Scan(
+			&message.Id,
+			&message.EventType,
+			&message.AccountId,
+			&message.Payload,
+			&message.Attempts,
+			&message.CreatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Id < messages[j].Id
+	})
+	return messages, nil
+}
+
+func insertOperation(ctx context.Context, tx *sql.Tx, accountID, amount int, operationType string, productID *int) error {
+	queryInsertOperation := `
+		INSERT INTO operations (account_id, amount, operation_type, product_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	op := entity.Operation{
+		AccountId:     accountID,
+		Amount:        amount,
+		OperationType: operationType,
+		ProductId:     productID,
+	}
+	err := tx.QueryRowContext(ctx, queryInsertOperation, accountID, amount, operationType, productID).Scan(
+		&op.Id,
+		&op.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	envelope, err := event.New(event.OperationType(op.OperationType), event.AccountKey(accountID), op.CreatedAt, event.OperationRecorded{
+		OperationId:   op.Id,
+		AccountId:     op.AccountId,
+		OperationType: op.OperationType,
+		Amount:        op.Amount,
+		BalanceEffect: op.BalanceEffect(),
+		ProductId:     op.ProductId,
+		CreatedAt:     op.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return enqueueOutbox(ctx, tx, accountID, envelope)
+}
+
+func enqueueOutbox(ctx context.Context, tx *sql.Tx, accountID int, envelope event.Envelope) error {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	queryInsertOutbox := `
+		INSERT INTO outbox (event_type, account_id, payload)
+		VALUES ($1, $2, $3)
+	`
+	_, err = tx.ExecContext(ctx, queryInsertOutbox, envelope.Type, accountID, data)
+	return err
+}
@@ -3,6 +3,21 @@ package repoerrs
 import "errors"
 
 var (
-	ErrDataDeleted      = errors.New("данные помечены как удалённые")
-	ErrNotEnoughBalance = errors.New("недостаточно средств")
+	ErrDataDeleted             = errors.New("данные помечены как удалённые")
+	ErrNotEnoughBalance        = errors.New("недостаточно средств")
+	ErrReservationConfirmed    = errors.New("резервация уже подтверждена")
+	ErrReservationRefunded     = errors.New("резервация уже возвращена")
+	ErrReservationExpired      = errors.New("срок резервации истёк")
+	ErrIllegalStatusTransition = errors.New("недопустимый переход статуса резервации")
+	ErrOrderReservationExists  = errors.New("резервация по заказу уже создана с другой суммой")
+	ErrInvalidAmount           = errors.New("некорректная сумма операции")
+	ErrInvalidOrder            = errors.New("некорректный состав заказа")
+	ErrIdempotencyConflict     = errors.New("ключ идемпотентности уже использован с другим запросом")
+	ErrIdempotencyInProgress   = errors.New("запрос с этим ключом идемпотентности ещё выполняется")
+	ErrUnbalancedTransaction   = errors.New("сумма проводок транзакции не равна нулю")
+	ErrInvalidCursor           = errors.New("некорректный курсор пагинации")
+	ErrJobNotFound             = errors.New("задача не зарегистрирована")
+	ErrJobRunning              = errors.New("задача уже выполняется")
+	ErrJobAlreadyRun           = errors.New("запуск задачи уже выполнен")
+	ErrInvalidPeriod           = errors.New("некорректный период отчёта")
 )
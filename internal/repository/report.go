@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"user_balance/internal/entity"
+)
+
+type ReportRepo struct {
+	pg *sql.DB
+}
+
+func NewReportRepo(pg *sql.DB) *ReportRepo {
+	return &ReportRepo{pg}
+}
+
+func (r *ReportRepo) StartReportFile(ctx context.Context, year, month int) (int, error) {
+	query := `
+		INSERT INTO reports (year, month, status)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (year, month) DO UPDATE
+		SET status = EXCLUDED.status, checksum = NULL, path = NULL, error = NULL, updated_at = NOW()
+		RETURNING id
+	`
+	var id int
+	err := r.pg.QueryRowContext(ctx, query, year, month, entity.ReportPending).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *ReportRepo) CompleteReportFile(ctx context.Context, id int, path, checksum string) error {
+	query := `
+		UPDATE reports
+		SET status = $2, path = $3, checksum = $4, error = NULL, updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.pg.ExecContext(ctx, query, id, entity.ReportCompleted, path, checksum)
+	return err
+}
+
+func (r *ReportRepo) FailReportFile(ctx context.Context, id int, reason string) error {
+	query := `
+		UPDATE reports
+		SET status = $2, error = $3, updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.pg.ExecContext(ctx, query, id, entity.ReportFailed, reason)
+	return err
+}
+
+func (r *ReportRepo) GetReportFile(ctx context.Context, id int) (entity.ReportFile, error) {
+	query := `
+		SELECT id, year, month, status, checksum, path, error, created_at, updated_at
+		FROM reports
+		WHERE id = $1
+	`
+	var report entity.ReportFile
+	err := r.pg.QueryRowContext(ctx, query, id).Scan(
+		&report.Id,
+		&report.Year,
+		&report.Month,
+		&report.Status,
+		&report.Checksum,
+		&report.Path,
+		&report.Error,
+		&report.CreatedAt,
+		&report.UpdatedAt,
+	)
+	if err != nil {
+		return entity.ReportFile{}, err
+	}
+	return report, nil
+}
+
+func (r *ReportRepo) ListReportFiles(ctx context.Context) ([]entity.ReportFile, error) {
+	query := `
+		SELECT id, year, month, status, checksum, path, error, created_at, updated_at
+		FROM reports
+		ORDER BY year DESC, month DESC
+	`
+	rows, err := r.pg.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []entity.ReportFile
+	for rows.Next() {
+		var report entity.ReportFile
+		err := rows.Scan(
+			&report.Id,
+			&report.Year,
+			&report.Month,
+			&report.Status,
+			&report.Checksum,
+			&report.Path,
+			&report.Error,
+			&report.CreatedAt,
+			&report.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (r *ReportRepo) GetReportExport(ctx context.Context, year, month int) (entity.ReportExport, error) {
+	queryInsert := `
+		INSERT INTO report_exports (year, month, status)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (year, month) DO NOTHING
+	`
+	if _, err := r.pg.ExecContext(ctx, queryInsert, year, month, entity.ReportExportInProgress); err != nil {
+		return entity.ReportExport{}, err
+	}
+
+	querySelect := `
+		SELECT year, month, status, last_operation_id, revision, created_at, updated_at, completed_at
+		FROM report_exports
+		WHERE year = $1 AND month = $2
+	`
+	var export entity.ReportExport
+	err := r.pg.QueryRowContext(ctx, querySelect, year, month).Scan(
+		&export.Year,
+		&export.Month,
+		&export.Status,
+		&export.LastOperationId,
+		&export.Revision,
+		&export.CreatedAt,
+		&export.UpdatedAt,
+		&export.CompletedAt,
+	)
+	if err != nil {
+		return entity.ReportExport{}, err
+	}
+	return export, nil
+}
+
+func (r *ReportRepo) ResetReportExport(ctx context.Context, year, month int) error {
+	query := `
+		UPDATE report_exports
+		SET status = $3, completed_at = NULL, updated_at = NOW()
+		WHERE year = $1 AND month = $2
+	`
+	_, err := r.pg.ExecContext(ctx, query, year, month, entity.ReportExportInProgress)
+	return err
+}
+
+func (r *ReportRepo) CompleteReportExport(ctx context.Context, year, month, lastOperationID int) error {
+	query := `
+		UPDATE report_exports
+		SET status = $3, last_operation_id = $4, revision = revision + 1, completed_at = NOW(), updated_at = NOW()
+		WHERE year = $1 AND month = $2
+	`
+	_, err := r.pg.ExecContext(ctx, query, year, month, entity.ReportExportCompleted, lastOperationID)
+	return err
+}
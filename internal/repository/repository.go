@@ -17,8 +17,15 @@ type Account interface {
 
 type Reservation interface {
 	CreateReservation(ctx context.Context, reservation entity.Reservation) (int, error)
+	CreateOrderReservation(ctx context.Context, order entity.OrderReservation) ([]int, error)
 	GetReservation(ctx context.Context, reservationID int) (entity.Reservation, error)
-	RefundReservation(ctx context.Context, reservationId int) error
+	GetReservationsByOrder(ctx context.Context, accountID, orderID int) ([]entity.Reservation, error)
+	RefundReservation(ctx context.Context, reservationId, amount int) error
+	CaptureReservation(ctx context.Context, reservationId, amount int) error
+	ConfirmOrder(ctx context.Context, accountId, orderId int) error
+	RefundOrder(ctx context.Context, accountId, orderId int) error
+	GetStaleReservations(ctx context.Context, ttl time.Duration) ([]int, error)
+	ExpireReservation(ctx context.Context, reservationId int) error
 }
 
 type Product interface {
@@ -28,6 +35,43 @@ type Product interface {
 
 type Operation interface {
 	GetMonthlyOperations(ctx context.Context, startDate, endDate time.Time) ([]entity.Operation, error)
+	GetAccountOperations(ctx context.Context, filter entity.OperationFilter) ([]entity.Operation, error)
+	GetBalanceBefore(ctx context.Context, accountID int, before time.Time) (int, error)
+	StreamAccountOperations(ctx context.Context, accountID int, from, to time.Time, fn func(entity.Operation) error) error
+	GetRevenueByProduct(ctx context.Context, startDate, endDate time.Time) ([]entity.RevenueReportItem, error)
+	GetRevenueWatermark(ctx context.Context, startDate, endDate time.Time) (int, error)
+}
+
+type Report interface {
+	StartReportFile(ctx context.Context, year, month int) (int, error)
+	CompleteReportFile(ctx context.Context, id int, path, checksum string) error
+	FailReportFile(ctx context.Context, id int, reason string) error
+	GetReportFile(ctx context.Context, id int) (entity.ReportFile, error)
+	ListReportFiles(ctx context.Context) ([]entity.ReportFile, error)
+	GetReportExport(ctx context.Context, year, month int) (entity.ReportExport, error)
+	ResetReportExport(ctx context.Context, year, month int) error
+	CompleteReportExport(ctx context.Context, year, month, lastOperationID int) error
+}
+
+type Outbox interface {
+	ProcessOutbox(ctx context.Context, limit int, lease time.Duration, publish func(entity.OutboxMessage) error) (int, error)
+}
+
+type Job interface {
+	LockJob(ctx context.Context, name string) (func(), bool, error)
+	StartJobRun(ctx context.Context, name string, trigger entity.JobTrigger, scheduledAt time.Time, instance string, args entity.JobArgs) (int64, bool, error)
+	FinishJobRun(ctx context.Context, id int64, status entity.JobRunStatus, rowsProcessed int, reason *string) error
+	GetJobRun(ctx context.Context, id int64) (entity.JobRun, error)
+	ListJobRuns(ctx context.Context, name string, limit int) ([]entity.JobRun, error)
+	FailStaleJobRuns(ctx context.Context, name, reason string) (int, error)
+	DeleteJobRunsBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+type Idempotency interface {
+	LockIdempotencyKey(ctx context.Context, key, requestHash string, lease, ttl time.Duration) (entity.IdempotencyRecord, bool, error)
+	SaveIdempotentResponse(ctx context.Context, key string, statusCode int, contentType string, response []byte, ttl time.Duration) error
+	DeleteIdempotencyKey(ctx context.Context, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int, error)
 }
 
 type Repository struct {
@@ -35,6 +79,10 @@ type Repository struct {
 	Product
 	Reservation
 	Operation
+	Report
+	Outbox
+	Job
+	Idempotency
 }
 
 func NewRepository(pg *sql.DB) *Repository {
@@ -43,5 +91,9 @@ func NewRepository(pg *sql.DB) *Repository {
 		Product:     NewProductRepo(pg),
 		Reservation: NewReservationRepo(pg),
 		Operation:   NewOperationRepo(pg),
+		Report:      NewReportRepo(pg),
+		Outbox:      NewOutboxRepo(pg),
+		Job:         NewJobRepo(pg),
+		Idempotency: NewIdempotencyRepo(pg),
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 	"user_balance/internal/entity"
 	"user_balance/internal/repository/repoerrs"
+	"user_balance/pkg/event"
 )
 
 type ReservationRepo struct {
@@ -24,16 +25,27 @@ func (r *ReservationRepo) CreateReservation(ctx context.Context, reservation ent
 
 	var balance int
 	var accountDeletedAt *time.Time
-	queryCheckAccount := "SELECT balance, deleted_at FROM accounts WHERE id = $1"
+	queryCheckAccount := "SELECT balance, deleted_at FROM accounts WHERE id = $1 FOR UPDATE"
 	err = tx.QueryRowContext(ctx, queryCheckAccount, reservation.AccountId).Scan(&balance, &accountDeletedAt)
 
 	if err != nil {
+		tx.Rollback()
 		return 0, err
 	}
+
+	if reservation.OrderId != nil {
+		existingID, err := findOrderReservation(ctx, tx, reservation)
+		if err != sql.ErrNoRows {
+			tx.Rollback()
+			return existingID, err
+		}
+	}
 	if accountDeletedAt != nil {
+		tx.Rollback()
 		return 0, repoerrs.ErrDataDeleted
 	}
 	if balance < reservation.Amount {
+		tx.Rollback()
 		return 0, repoerrs.ErrNotEnoughBalance
 	}
 
@@ -41,46 +53,67 @@ func (r *ReservationRepo) CreateReservation(ctx context.Context, reservation ent
 	queryCheckProduct := "SELECT deleted_at FROM products WHERE id = $1"
 	err = tx.QueryRowContext(ctx, queryCheckProduct, reservation.ProductId).Scan(&productDeletedAt)
 	if err != nil {
+		tx.Rollback()
 		return 0, err
 	}
 	if productDeletedAt != nil {
+		tx.Rollback()
 		return 0, repoerrs.ErrDataDeleted
 	}
 
-	queryUpdateBalance := `
-		UPDATE accounts
-		SET balance = balance - $1, updated_at = NOW()
-		WHERE id = $2
-	`
-	_, err = tx.ExecContext(ctx, queryUpdateBalance, reservation.Amount, reservation.AccountId)
-	if err != nil {
-		return 0, err
-	}
-
 	var reservationID int
 	queryInsertReservation := `
-		INSERT INTO reservations (account_id, product_id, amount)
-		VALUES ($1, $2, $3)
+		INSERT INTO reservations (account_id, product_id, order_id, amount, remaining)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (account_id, product_id, order_id) DO NOTHING
 		RETURNING id
 	`
 
 	err = tx.QueryRowContext(ctx, queryInsertReservation,
-		reservation.AccountId, reservation.ProductId, reservation.Amount,
+		reservation.AccountId, reservation.ProductId, reservation.OrderId, reservation.Amount,
 	).Scan(&reservationID)
 
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return findOrderReservation(ctx, r.pg, reservation)
+	}
 	if err != nil {
+		tx.Rollback()
 		return 0, err
 	}
 
-	queryInsertOperation := `
-		INSERT INTO operations (account_id, amount, operation_type, product_id)
-		VALUES ($1, $2, $3, $4)
+	queryUpdateBalance := `
+		UPDATE accounts
+		SET balance = balance - $1, held = held + $1, updated_at = NOW()
+		WHERE id = $2
 	`
+	_, err = tx.ExecContext(ctx, queryUpdateBalance, reservation.Amount, reservation.AccountId)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = insertOperation(ctx, tx, reservation.AccountId, reservation.Amount, "reservation", &reservation.ProductId)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
 
-	_, err = tx.ExecContext(ctx, queryInsertOperation,
-		reservation.AccountId, reservation.Amount, "reservation", reservation.ProductId,
+	reservation.Id = reservationID
+	reservation.Remaining = reservation.Amount
+	reservation.Status = entity.ReservationPending
+	err = insertReservationEvent(ctx, tx, event.TypeReservationCreated, reservation, reservation.Amount)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = postLedgerTransaction(ctx, tx, "reservation", &reservationID,
+		customerPosting(reservation.AccountId, -reservation.Amount),
+		systemPosting(ledgerReservationHolds, reservation.Amount),
 	)
 	if err != nil {
+		tx.Rollback()
 		return 0, err
 	}
 
@@ -92,9 +125,137 @@ func (r *ReservationRepo) CreateReservation(ctx context.Context, reservation ent
 	return reservationID, nil
 }
 
+func (r *ReservationRepo) CreateOrderReservation(ctx context.Context, order entity.OrderReservation) ([]int, error) {
+	tx, err := r.pg.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0
+	for _, item := range order.Items {
+		total += item.Amount
+	}
+
+	var balance int
+	var accountDeletedAt *time.Time
+	queryCheckAccount := "SELECT balance, deleted_at FROM accounts WHERE id = $1 FOR UPDATE"
+	err = tx.QueryRowContext(ctx, queryCheckAccount, order.AccountId).Scan(&balance, &accountDeletedAt)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	existing, err := findOrderReservations(ctx, tx, order.AccountId, order.OrderId)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if len(existing) > 0 {
+		tx.Rollback()
+		return matchOrderReservations(existing, order)
+	}
+	if accountDeletedAt != nil {
+		tx.Rollback()
+		return nil, repoerrs.ErrDataDeleted
+	}
+	if balance < total {
+		tx.Rollback()
+		return nil, repoerrs.ErrNotEnoughBalance
+	}
+
+	queryCheckProduct := "SELECT deleted_at FROM products WHERE id = $1"
+	queryInsertReservation := `
+		INSERT INTO reservations (account_id, product_id, order_id, amount, remaining)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (account_id, product_id, order_id) DO NOTHING
+		RETURNING id
+	`
+
+	ids := make([]int, 0, len(order.Items))
+	for _, item := range order.Items {
+		var productDeletedAt *time.Time
+		err = tx.QueryRowContext(ctx, queryCheckProduct, item.ProductId).Scan(&productDeletedAt)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if productDeletedAt != nil {
+			tx.Rollback()
+			return nil, repoerrs.ErrDataDeleted
+		}
+
+		var reservationID int
+		err = tx.QueryRowContext(ctx, queryInsertReservation,
+			order.AccountId, item.ProductId, order.OrderId, item.Amount,
+		).Scan(&reservationID)
+		if err == sql.ErrNoRows {
+			tx.Rollback()
+			existing, err := findOrderReservations(ctx, r.pg, order.AccountId, order.OrderId)
+			if err != nil {
+				return nil, err
+			}
+			return matchOrderReservations(existing, order)
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		err = insertOperation(ctx, tx, order.AccountId, item.Amount, "reservation", &item.ProductId)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		orderID := order.OrderId
+		err = insertReservationEvent(ctx, tx, event.TypeReservationCreated, entity.Reservation{
+			Id:        reservationID,
+			AccountId: order.AccountId,
+			ProductId: item.ProductId,
+			OrderId:   &orderID,
+			Amount:    item.Amount,
+			Remaining: item.Amount,
+			Status:    entity.ReservationPending,
+		}, item.Amount)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		err = postLedgerTransaction(ctx, tx, "reservation", &reservationID,
+			customerPosting(order.AccountId, -item.Amount),
+			systemPosting(ledgerReservationHolds, item.Amount),
+		)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		ids = append(ids, reservationID)
+	}
+
+	queryUpdateBalance := `
+		UPDATE accounts
+		SET balance = balance - $1, held = held + $1, updated_at = NOW()
+		WHERE id = $2
+	`
+	_, err = tx.ExecContext(ctx, queryUpdateBalance, total, order.AccountId)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
 func (r *ReservationRepo) GetReservation(ctx context.Context, reservationID int) (entity.Reservation, error) {
 	queryGetReservation := `
-    SELECT id, account_id, product_id, amount, created_at, deleted_at
+    SELECT id, account_id, product_id, order_id, amount, remaining, status, created_at, updated_at, confirmed_at, deleted_at
     FROM reservations
     WHERE id = $1
     `
@@ -104,8 +265,13 @@ func (r *ReservationRepo) GetReservation(ctx context.Context, reservationID int)
 		&reservation.Id,
 		&reservation.AccountId,
 		&reservation.ProductId,
+		&reservation.OrderId,
 		&reservation.Amount,
+		&reservation.Remaining,
+		&reservation.Status,
 		&reservation.CreatedAt,
+		&reservation.UpdatedAt,
+		&reservation.ConfirmedAt,
 		&reservation.DeletedAt,
 	)
 
@@ -120,86 +286,438 @@ func (r *ReservationRepo) GetReservation(ctx context.Context, reservationID int)
 	return reservation, nil
 }
 
-func (r *ReservationRepo) RefundReservation(ctx context.Context, reservationId int) error {
+func (r *ReservationRepo) GetReservationsByOrder(ctx context.Context, accountID, orderID int) ([]entity.Reservation, error) {
+	queryGetReservations := `
+    SELECT id, account_id, product_id, order_id, amount, remaining, status, created_at, updated_at, confirmed_at, deleted_at
+    FROM reservations
+    WHERE account_id = $1 AND order_id = $2 AND deleted_at IS NULL
+    ORDER BY id
+    `
+
+	rows, err := r.pg.QueryContext(ctx, queryGetReservations, accountID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reservations []entity.Reservation
+	for rows.Next() {
+		var reservation entity.Reservation
+		err := rows.Scan(
+			&reservation.Id,
+			&reservation.AccountId,
+			&reservation.ProductId,
+			&reservation.OrderId,
+			&reservation.Amount,
+			&reservation.Remaining,
+			&reservation.Status,
+			&reservation.CreatedAt,
+			&reservation.UpdatedAt,
+			&reservation.ConfirmedAt,
+			&reservation.DeletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (r *ReservationRepo) RefundReservation(ctx context.Context, reservationId, amount int) error {
+	return r.withPendingReservation(ctx, reservationId, func(tx *sql.Tx, reservation entity.Reservation) error {
+		if amount == 0 {
+			amount = reservation.Remaining
+		}
+		return releaseReservation(ctx, tx, reservation, amount, entity.ReservationRefunded, "refund")
+	})
+}
+
+func (r *ReservationRepo) ExpireReservation(ctx context.Context, reservationId int) error {
+	return r.withPendingReservation(ctx, reservationId, func(tx *sql.Tx, reservation entity.Reservation) error {
+		return releaseReservation(ctx, tx, reservation, reservation.Remaining, entity.ReservationExpired, "expired")
+	})
+}
+
+func (r *ReservationRepo) CaptureReservation(ctx context.Context, reservationId, amount int) error {
+	return r.withPendingReservation(ctx, reservationId, func(tx *sql.Tx, reservation entity.Reservation) error {
+		if amount == 0 {
+			amount = reservation.Remaining
+		}
+		return captureReservation(ctx, tx, reservation, amount)
+	})
+}
+
+func (r *ReservationRepo) ConfirmOrder(ctx context.Context, accountId, orderId int) error {
+	return r.withPendingOrder(ctx, accountId, orderId, func(tx *sql.Tx, reservation entity.Reservation) error {
+		return captureReservation(ctx, tx, reservation, reservation.Remaining)
+	})
+}
+
+func (r *ReservationRepo) RefundOrder(ctx context.Context, accountId, orderId int) error {
+	return r.withPendingOrder(ctx, accountId, orderId, func(tx *sql.Tx, reservation entity.Reservation) error {
+		return releaseReservation(ctx, tx, reservation, reservation.Remaining, entity.ReservationRefunded, "refund")
+	})
+}
+
+func (r *ReservationRepo) GetStaleReservations(ctx context.Context, ttl time.Duration) ([]int, error) {
+	queryGetStale := `
+	SELECT id
+	FROM reservations
+	WHERE status = $1 AND deleted_at IS NULL AND created_at < NOW() - make_interval(secs => $2)
+	ORDER BY id
+	`
+
+	rows, err := r.pg.QueryContext(ctx, queryGetStale, entity.ReservationPending, ttl.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *ReservationRepo) withPendingReservation(ctx context.Context, reservationId int, fn func(tx *sql.Tx, reservation entity.Reservation) error) error {
 	tx, err := r.pg.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	queryGetReservation := `
-	SELECT account_id, amount, product_id, created_at, deleted_at FROM reservations WHERE id = $1
+	reservation, err := lockPendingReservation(ctx, tx, reservationId)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := fn(tx, reservation); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *ReservationRepo) withPendingOrder(ctx context.Context, accountId, orderId int, fn func(tx *sql.Tx, reservation entity.Reservation) error) error {
+	tx, err := r.pg.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	queryLockOrder := `
+	SELECT id
+	FROM reservations
+	WHERE account_id = $1 AND order_id = $2 AND status = $3 AND deleted_at IS NULL
+	ORDER BY id
+	FOR UPDATE
 	`
-	var reservation entity.Reservation
-	err = tx.QueryRowContext(ctx, queryGetReservation, reservationId).Scan(
-		&reservation.AccountId,
-		&reservation.Amount,
-		&reservation.ProductId,
-		&reservation.CreatedAt,
-		&reservation.DeletedAt,
-	)
+	rows, err := tx.QueryContext(ctx, queryLockOrder, accountId, orderId, entity.ReservationPending)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	if reservation.DeletedAt != nil {
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	if len(ids) == 0 {
+		tx.Rollback()
+		return repoerrs.ErrIllegalStatusTransition
+	}
+
+	for _, id := range ids {
+		reservation, err := lockPendingReservation(ctx, tx, id)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err := fn(tx, reservation); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func releaseReservation(ctx context.Context, tx *sql.Tx, reservation entity.Reservation, amount int, status entity.ReservationStatus, operationType string) error {
+	if amount <= 0 || amount > reservation.Remaining {
+		return repoerrs.ErrInvalidAmount
+	}
+
 	queryGetAccount := `
 	SELECT deleted_at FROM accounts WHERE id = $1
 	`
 	var accountDeletedAt *time.Time
-	err = tx.QueryRowContext(ctx, queryGetAccount, reservation.AccountId).Scan(&accountDeletedAt)
+	err := tx.QueryRowContext(ctx, queryGetAccount, reservation.AccountId).Scan(&accountDeletedAt)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
 	if accountDeletedAt != nil {
-		tx.Rollback()
-		return err
+		return repoerrs.ErrDataDeleted
+	}
+
+	remaining := reservation.Remaining - amount
+	if remaining > 0 {
+		status = entity.ReservationPending
 	}
 
 	queryUpdateReservation := `
 	UPDATE reservations
-	SET deleted_at = NOW(), updated_at = NOW()
+	SET status = $2, remaining = $3, updated_at = NOW()
 	WHERE id = $1
 	`
-	_, err = tx.ExecContext(ctx, queryUpdateReservation, reservationId)
+	_, err = tx.ExecContext(ctx, queryUpdateReservation, reservation.Id, status, remaining)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
 	queryUpdateBalance := `
 	UPDATE accounts
-	SET balance = balance + $1, updated_at = NOW()
+	SET balance = balance + $1, held = held - $1, updated_at = NOW()
 	WHERE id = $2
 	`
-	_, err = tx.ExecContext(ctx, queryUpdateBalance, reservation.Amount, reservation.AccountId)
+	_, err = tx.ExecContext(ctx, queryUpdateBalance, amount, reservation.AccountId)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
-	queryInsertOperation := `
-	INSERT INTO operations (account_id, amount, operation_type, product_id)
-	VALUES ($1, $2, $3, $4)
-	`
-	_, err = tx.ExecContext(ctx, queryInsertOperation,
-		reservation.AccountId, reservation.Amount, "refund", reservation.ProductId,
+	err = insertOperation(ctx, tx, reservation.AccountId, amount, operationType, &reservation.ProductId)
+	if err != nil {
+		return err
+	}
+
+	eventType := event.TypeReservationRefunded
+	if operationType == "expired" {
+		eventType = event.TypeReservationExpired
+	}
+	reservation.Remaining = remaining
+	reservation.Status = status
+	err = insertReservationEvent(ctx, tx, eventType, reservation, amount)
+	if err != nil {
+		return err
+	}
+
+	return postLedgerTransaction(ctx, tx, operationType, &reservation.Id,
+		systemPosting(ledgerReservationHolds, -amount),
+		customerPosting(reservation.AccountId, amount),
 	)
+}
+
+func captureReservation(ctx context.Context, tx *sql.Tx, reservation entity.Reservation, amount int) error {
+	if amount <= 0 || amount > reservation.Remaining {
+		return repoerrs.ErrInvalidAmount
+	}
+
+	queryUpdateReservation := `
+	UPDATE reservations
+	SET status = $2, remaining = 0, confirmed_at = NOW(), updated_at = NOW()
+	WHERE id = $1
+	`
+	_, err := tx.ExecContext(ctx, queryUpdateReservation, reservation.Id, entity.ReservationConfirmed)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
-	err = tx.Commit()
+	err = insertOperation(ctx, tx, reservation.AccountId, amount, "revenue", &reservation.ProductId)
+	if err != nil {
+		return err
+	}
+
+	returned := reservation.Remaining - amount
+	queryUpdateBalance := `
+	UPDATE accounts
+	SET balance = balance + $1, held = held - $2, updated_at = NOW()
+	WHERE id = $3
+	`
+	_, err = tx.ExecContext(ctx, queryUpdateBalance, returned, reservation.Remaining, reservation.AccountId)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if returned > 0 {
+		err = insertOperation(ctx, tx, reservation.AccountId, returned, "refund", &reservation.ProductId)
+		if err != nil {
+			return err
+		}
+	}
+
+	confirmed := reservation
+	confirmed.Remaining = 0
+	confirmed.Status = entity.ReservationConfirmed
+	err = insertReservationEvent(ctx, tx, event.TypeReservationConfirmed, confirmed, amount)
+	if err != nil {
+		return err
+	}
+
+	return postLedgerTransaction(ctx, tx, "revenue", &reservation.Id,
+		systemPosting(ledgerReservationHolds, -reservation.Remaining),
+		systemPosting(ledgerRevenue, amount),
+		customerPosting(reservation.AccountId, returned),
+	)
+}
+
+func lockPendingReservation(ctx context.Context, tx *sql.Tx, reservationId int) (entity.Reservation, error) {
+	queryGetReservation := `
+	SELECT id, account_id, amount, remaining, product_id, order_id, status, created_at, deleted_at
+	FROM reservations
+	WHERE id = $1
+	FOR UPDATE
+	`
+	var reservation entity.Reservation
+	err := tx.QueryRowContext(ctx, queryGetReservation, reservationId).Scan(
+		&reservation.Id,
+		&reservation.AccountId,
+		&reservation.Amount,
+		&reservation.Remaining,
+		&reservation.ProductId,
+		&reservation.OrderId,
+		&reservation.Status,
+		&reservation.CreatedAt,
+		&reservation.DeletedAt,
+	)
+	if err != nil {
+		return entity.Reservation{}, err
+	}
+
+	if reservation.DeletedAt != nil {
+		return entity.Reservation{}, repoerrs.ErrDataDeleted
+	}
+
+	switch reservation.Status {
+	case entity.ReservationPending:
+	case entity.ReservationConfirmed:
+		return entity.Reservation{}, repoerrs.ErrReservationConfirmed
+	case entity.ReservationRefunded:
+		return entity.Reservation{}, repoerrs.ErrReservationRefunded
+	case entity.ReservationExpired:
+		return entity.Reservation{}, repoerrs.ErrReservationExpired
+	default:
+		return entity.Reservation{}, repoerrs.ErrIllegalStatusTransition
+	}
+
+	return reservation, nil
+}
+
+func insertReservationEvent(ctx context.Context, tx *sql.Tx, eventType string, reservation entity.Reservation, amount int) error {
+	occurredAt := time.Now().UTC()
+	envelope, err := event.New(eventType, event.AccountKey(reservation.AccountId), occurredAt, event.ReservationChanged{
+		ReservationId: reservation.Id,
+		AccountId:     reservation.AccountId,
+		ProductId:     reservation.ProductId,
+		OrderId:       reservation.OrderId,
+		Amount:        amount,
+		Remaining:     reservation.Remaining,
+		Status:        string(reservation.Status),
+		OccurredAt:    occurredAt,
+	})
+	if err != nil {
+		return err
+	}
+	return enqueueOutbox(ctx, tx, reservation.AccountId, envelope)
+}
+
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func findOrderReservation(ctx context.Context, q rowQuerier, reservation entity.Reservation) (int, error) {
+	queryFindReservation := `
+	SELECT id, amount
+	FROM reservations
+	WHERE account_id = $1 AND product_id = $2 AND order_id = $3
+	`
+	var id, amount int
+	err := q.QueryRowContext(ctx, queryFindReservation,
+		reservation.AccountId, reservation.ProductId, reservation.OrderId,
+	).Scan(&id, &amount)
+	if err != nil {
+		return 0, err
+	}
+
+	if amount != reservation.Amount {
+		return 0, repoerrs.ErrOrderReservationExists
+	}
+
+	return id, nil
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func findOrderReservations(ctx context.Context, q querier, accountId, orderId int) ([]entity.Reservation, error) {
+	queryFindReservations := `
+	SELECT id, product_id, amount
+	FROM reservations
+	WHERE account_id = $1 AND order_id = $2
+	ORDER BY id
+	`
+	rows, err := q.QueryContext(ctx, queryFindReservations, accountId, orderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reservations []entity.Reservation
+	for rows.Next() {
+		var reservation entity.Reservation
+		if err := rows.Scan(&reservation.Id, &reservation.ProductId, &reservation.Amount); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func matchOrderReservations(existing []entity.Reservation, order entity.OrderReservation) ([]int, error) {
+	if len(existing) != len(order.Items) {
+		return nil, repoerrs.ErrOrderReservationExists
+	}
+
+	byProduct := make(map[int]entity.Reservation, len(existing))
+	for _, reservation := range existing {
+		byProduct[reservation.ProductId] = reservation
+	}
+
+	ids := make([]int, 0, len(order.Items))
+	for _, item := range order.Items {
+		reservation, ok := byProduct[item.ProductId]
+		if !ok || reservation.Amount != item.Amount {
+			return nil, repoerrs.ErrOrderReservationExists
+		}
+		ids = append(ids, reservation.Id)
+	}
+	return ids, nil
 }
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"user_balance/internal/entity"
+	"user_balance/internal/repository"
+	"user_balance/internal/repository/repoerrs"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	idempotencyLease = time.Minute
+	idempotencyTTL   = 24 * time.Hour
+)
+
+type IdempotencyService struct {
+	repo   repository.Idempotency
+	logger *logrus.Logger
+}
+
+func NewIdempotencyService(repo repository.Idempotency, logger *logrus.Logger) *IdempotencyService {
+	return &IdempotencyService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *IdempotencyService) Begin(ctx context.Context, key, requestHash string) (*entity.IdempotencyRecord, error) {
+	record, created, err := s.repo.LockIdempotencyKey(ctx, key, requestHash, idempotencyLease, idempotencyTTL)
+	if err != nil {
+		err = fmt.Errorf("ошибка при блокировке ключа идемпотентности %s: %w", key, err)
+		s.logger.Error(err)
+		return nil, err
+	}
+
+	if created {
+		return nil, nil
+	}
+
+	if record.RequestHash != requestHash {
+		s.logger.Warnf("Ключ идемпотентности %s использован с другим запросом", key)
+		return nil, repoerrs.ErrIdempotencyConflict
+	}
+
+	if record.CompletedAt == nil || record.StatusCode == nil {
+		s.logger.Warnf("Запрос с ключом идемпотентности %s ещё выполняется", key)
+		return nil, repoerrs.ErrIdempotencyInProgress
+	}
+
+	s.logger.Infof("Повтор сохранённого ответа для ключа идемпотентности %s", key)
+	return &record, nil
+}
+
+func (s *IdempotencyService) Complete(ctx context.Context, key string, statusCode int, contentType string, response []byte) error {
+	if err := s.repo.SaveIdempotentResponse(ctx, key, statusCode, contentType, response, idempotencyTTL); err != nil {
+		err = fmt.Errorf("ошибка при сохранении ответа для ключа идемпотентности %s: %w", key, err)
+		s.logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (s *IdempotencyService) Release(ctx context.Context, key string) error {
+	if err := s.repo.DeleteIdempotencyKey(ctx, key); err != nil {
+		err = fmt.Errorf("ошибка при освобождении ключа идемпотентности %s: %w", key, err)
+		s.logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (s *IdempotencyService) Cleanup(ctx context.Context) (int, error) {
+	deleted, err := s.repo.DeleteExpiredIdempotencyKeys(ctx)
+	if err != nil {
+		err = fmt.Errorf("ошибка при удалении просроченных ключей идемпотентности: %w", err)
+		s.logger.Error(err)
+		return 0, err
+	}
+	s.logger.Infof("Удалено просроченных ключей идемпотентности: %d", deleted)
+	return deleted, nil
+}
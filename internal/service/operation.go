@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"user_balance/internal/entity"
+	"user_balance/internal/repository"
+	"user_balance/internal/repository/repoerrs"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultOperationsLimit = 50
+	maxOperationsLimit     = 500
+)
+
+type OperationService struct {
+	repo     repository.Operation
+	accounts repository.Account
+	logger   *logrus.Logger
+}
+
+func NewOperationService(repo repository.Operation, accounts repository.Account, logger *logrus.Logger) *OperationService {
+	return &OperationService{
+		repo:     repo,
+		accounts: accounts,
+		logger:   logger,
+	}
+}
+
+func (s *OperationService) GetAccountOperations(ctx context.Context, filter entity.OperationFilter, cursor string) (entity.OperationPage, error) {
+	s.logger.Infof("Получение операций аккаунта с ID %d: %+v", filter.AccountId, filter)
+
+	if filter.SortBy == "" {
+		filter.SortBy = entity.OperationSortByDate
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultOperationsLimit
+	}
+	if filter.Limit > maxOperationsLimit {
+		filter.Limit = maxOperationsLimit
+	}
+
+	if cursor != "" {
+		after, err := decodeOperationCursor(cursor, filter.SortBy)
+		if err != nil {
+			err = fmt.Errorf("ошибка при получении операций аккаунта с ID %d: %w", filter.AccountId, err)
+			s.logger.Error(err)
+			return entity.OperationPage{}, err
+		}
+		filter.After = &after
+	}
+
+	if _, err := s.accounts.GetAccount(ctx, filter.AccountId); err != nil {
+		err = fmt.Errorf("ошибка при получении аккаунта с ID %d: %w", filter.AccountId, err)
+		s.logger.Error(err)
+		return entity.OperationPage{}, err
+	}
+
+	limit := filter.Limit
+	filter.Limit++
+	operations, err := s.repo.GetAccountOperations(ctx, filter)
+	if err != nil {
+		err = fmt.Errorf("ошибка при получении операций аккаунта с ID %d: %w", filter.AccountId, err)
+		s.logger.Error(err)
+		return entity.OperationPage{}, err
+	}
+
+	page := entity.OperationPage{Operations: operations}
+	if len(operations) > limit {
+		page.Operations = operations[:limit]
+		page.NextCursor = encodeOperationCursor(page.Operations[limit-1], filter.SortBy)
+	}
+	if page.Operations == nil {
+		page.Operations = []entity.Operation{}
+	}
+
+	s.logger.Infof("Операции аккаунта с ID %d успешно получены: %d шт.", filter.AccountId, len(page.Operations))
+	return page, nil
+}
+
+func encodeOperationCursor(op entity.Operation, sortBy string) string {
+	value := op.CreatedAt.Format(time.RFC3339Nano)
+	if sortBy == entity.OperationSortByAmount {
+		value = strconv.Itoa(op.Amount)
+	}
+	raw := sortBy + "|" + value + "|" + strconv.Itoa(op.Id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeOperationCursor(cursor, sortBy string) (entity.OperationCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return entity.OperationCursor{}, repoerrs.ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || parts[0] != sortBy {
+		return entity.OperationCursor{}, repoerrs.ErrInvalidCursor
+	}
+
+	var after entity.OperationCursor
+	after.Id, err = strconv.Atoi(parts[2])
+	if err != nil {
+		return entity.OperationCursor{}, repoerrs.ErrInvalidCursor
+	}
+
+	if sortBy == entity.OperationSortByAmount {
+		after.Amount, err = strconv.Atoi(parts[1])
+	} else {
+		after.CreatedAt, err = time.Parse(time.RFC3339Nano, parts[1])
+	}
+	if err != nil {
+		return entity.OperationCursor{}, repoerrs.ErrInvalidCursor
+	}
+
+	return after, nil
+}
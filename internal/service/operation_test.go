@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"io"
+	"sort"
+	"testing"
+	"time"
+	"user_balance/internal/entity"
+	"user_balance/internal/repository"
+	"user_balance/internal/repository/repoerrs"
+
+	"github.com/sirupsen/logrus"
+)
+
+type fakeOperationRepo struct {
+	repository.Operation
+	operations []entity.Operation
+}
+
+func (f *fakeOperationRepo) GetAccountOperations(ctx context.Context, filter entity.OperationFilter) ([]entity.Operation, error) {
+	key := func(op entity.Operation) (int64, int) {
+		if filter.SortBy == entity.OperationSortByAmount {
+			return int64(op.Amount), op.Id
+		}
+		return op.CreatedAt.UnixNano(), op.Id
+	}
+	less := func(a1 int64, a2 int, b1 int64, b2 int) bool {
+		if a1 != b1 {
+			return a1 < b1
+		}
+		return a2 < b2
+	}
+
+	sorted := append([]entity.Operation(nil), f.operations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		i1, i2 := key(sorted[i])
+		j1, j2 := key(sorted[j])
+		if filter.Desc {
+			return less(j1, j2, i1, i2)
+		}
+		return less(i1, i2, j1, j2)
+	})
+
+	var page []entity.Operation
+	for _, op := range sorted {
+		if filter.After != nil {
+			c1 := filter.After.CreatedAt.UnixNano()
+			if filter.SortBy == entity.OperationSortByAmount {
+				c1 = int64(filter.After.Amount)
+			}
+			o1, o2 := key(op)
+			if filter.Desc && !less(o1, o2, c1, filter.After.Id) || !filter.Desc && !less(c1, filter.After.Id, o1, o2) {
+				continue
+			}
+		}
+		page = append(page, op)
+		if len(page) == filter.Limit {
+			break
+		}
+	}
+	return page, nil
+}
+
+type fakeAccountRepo struct {
+	repository.Account
+	err error
+}
+
+func (f *fakeAccountRepo) GetAccount(ctx context.Context, id int) (entity.Account, error) {
+	return entity.Account{Id: id}, f.err
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func testOperations() []entity.Operation {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	amounts := []int{100, 50, 100, 300, 50, 100, 10}
+	operations := make([]entity.Operation, 0, len(amounts))
+	for i, amount := range amounts {
+		operations = append(operations, entity.Operation{
+			Id:        i + 1,
+			AccountId: 1,
+			Amount:    amount,
+			CreatedAt: start.Add(time.Duration(i/2) * time.Hour),
+		})
+	}
+	return operations
+}
+
+func TestGetAccountOperationsCursorPagination(t *testing.T) {
+	tests := []struct {
+		name   string
+		sortBy string
+		desc   bool
+		limit  int
+	}{
+		{name: "по дате по убыванию", sortBy: entity.OperationSortByDate, desc: true, limit: 2},
+		{name: "по дате по возрастанию", sortBy: entity.OperationSortByDate, limit: 3},
+		{name: "по сумме по убыванию", sortBy: entity.OperationSortByAmount, desc: true, limit: 2},
+		{name: "по сумме по возрастанию", sortBy: entity.OperationSortByAmount, limit: 1},
+		{name: "одна страница", sortBy: entity.OperationSortByDate, desc: true, limit: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeOperationRepo{operations: testOperations()}
+			s := NewOperationService(repo, &fakeAccountRepo{}, testLogger())
+
+			all, err := repo.GetAccountOperations(context.Background(), entity.OperationFilter{SortBy: tt.sortBy, Desc: tt.desc, Limit: len(repo.operations)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []entity.Operation
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > len(all) {
+					t.Fatal("пагинация не завершилась")
+				}
+				page, err := s.GetAccountOperations(context.Background(), entity.OperationFilter{AccountId: 1, SortBy: tt.sortBy, Desc: tt.desc, Limit: tt.limit}, cursor)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(page.Operations) > tt.limit {
+					t.Fatalf("страница содержит %d операций, лимит %d", len(page.Operations), tt.limit)
+				}
+				got = append(got, page.Operations...)
+				if page.NextCursor == "" {
+					break
+				}
+				cursor = page.NextCursor
+			}
+
+			if len(got) != len(all) {
+				t.Fatalf("получено %d операций, ожидалось %d", len(got), len(all))
+			}
+			for i := range all {
+				if got[i].Id != all[i].Id {
+					t.Errorf("позиция %d: операция %d, ожидалась %d", i, got[i].Id, all[i].Id)
+				}
+			}
+		})
+	}
+}
+
+func TestGetAccountOperationsErrors(t *testing.T) {
+	valid := encodeOperationCursor(entity.Operation{Id: 3, Amount: 100}, entity.OperationSortByAmount)
+
+	tests := []struct {
+		name       string
+		sortBy     string
+		cursor     string
+		accountErr error
+		wantErr    error
+	}{
+		{name: "курсор не в base64", cursor: "!!!", wantErr: repoerrs.ErrInvalidCursor},
+		{name: "курсор другой сортировки", sortBy: entity.OperationSortByDate, cursor: valid, wantErr: repoerrs.ErrInvalidCursor},
+		{name: "повреждённый курсор", cursor: base64.RawURLEncoding.EncodeToString([]byte("date|x|1")), wantErr: repoerrs.ErrInvalidCursor},
+		{name: "неизвестный аккаунт", accountErr: sql.ErrNoRows, wantErr: sql.ErrNoRows},
+		{name: "удалённый аккаунт", accountErr: repoerrs.ErrDataDeleted, wantErr: repoerrs.ErrDataDeleted},
+		{name: "корректный курсор", sortBy: entity.OperationSortByAmount, cursor: valid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewOperationService(&fakeOperationRepo{operations: testOperations()}, &fakeAccountRepo{err: tt.accountErr}, testLogger())
+			_, err := s.GetAccountOperations(context.Background(), entity.OperationFilter{AccountId: 1, SortBy: tt.sortBy}, tt.cursor)
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ошибка %v, ожидалась %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"user_balance/internal/entity"
+	"user_balance/internal/repository"
+	"user_balance/internal/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ReportService struct {
+	operations repository.Operation
+	reports    repository.Report
+	files      storage.Storage
+	logger     *logrus.Logger
+}
+
+func NewReportService(operations repository.Operation, reports repository.Report, files storage.Storage, logger *logrus.Logger) *ReportService {
+	return &ReportService{
+		operations: operations,
+		reports:    reports,
+		files:      files,
+		logger:     logger,
+	}
+}
+
+func (s *ReportService) GetRevenueReport(ctx context.Context, year int, month time.Month) (entity.RevenueReport, error) {
+	s.logger.Infof("Формирование отчёта о выручке за %02d.%d", month, year)
+
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 1, 0)
+
+	lastID, err := s.operations.GetRevenueWatermark(ctx, start, end)
+	if err != nil {
+		err = fmt.Errorf("ошибка при получении последней операции выручки за %02d.%d: %w", month, year, err)
+		s.logger.Error(err)
+		return entity.RevenueReport{}, err
+	}
+
+	items, err := s.operations.GetRevenueByProduct(ctx, start, end)
+	if err != nil {
+		err = fmt.Errorf("ошибка при формировании отчёта о выручке за %02d.%d: %w", month, year, err)
+		s.logger.Error(err)
+		return entity.RevenueReport{}, err
+	}
+
+	report := entity.RevenueReport{
+		Year:            year,
+		Month:           int(month),
+		Items:           items,
+		LastOperationId: lastID,
+		GeneratedAt:     time.Now(),
+	}
+	if report.Items == nil {
+		report.Items = []entity.RevenueReportItem{}
+	}
+	for _, item := range items {
+		report.Total += item.Revenue
+	}
+
+	s.logger.Infof("Отчёт о выручке за %02d.%d сформирован: %d продуктов, итого %d", month, year, len(items), report.Total)
+	return report, nil
+}
+
+func (s *ReportService) SaveRevenueReport(ctx context.Context, report entity.RevenueReport) (entity.ReportFile, error) {
+	s.logger.Infof("Сохранение файла отчёта о выручке за %02d.%d", report.Month, report.Year)
+
+	id, err := s.reports.StartReportFile(ctx, report.Year, report.Month)
+	if err != nil {
+		err = fmt.Errorf("ошибка при регистрации файла отчёта за %02d.%d: %w", report.Month, report.Year, err)
+		s.logger.Error(err)
+		return entity.ReportFile{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeRevenueReportCSV(&buf, report); err != nil {
+		return entity.ReportFile{}, s.failReportFile(ctx, id, report, err)
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	checksum := hex.EncodeToString(sum[:])
+	path := fmt.Sprintf("revenue/%d-%02d.csv", report.Year, report.Month)
+
+	if err := s.files.Save(ctx, path, &buf); err != nil {
+		return entity.ReportFile{}, s.failReportFile(ctx, id, report, err)
+	}
+
+	if err := s.reports.CompleteReportFile(ctx, id, path, checksum); err != nil {
+		return entity.ReportFile{}, s.failReportFile(ctx, id, report, err)
+	}
+
+	file, err := s.reports.GetReportFile(ctx, id)
+	if err != nil {
+		err = fmt.Errorf("ошибка при получении файла отчёта с ID %d: %w", id, err)
+		s.logger.Error(err)
+		return entity.ReportFile{}, err
+	}
+
+	s.logger.Infof("Файл отчёта о выручке за %02d.%d сохранён: %s", report.Month, report.Year, path)
+	return file, nil
+}
+
+func (s *ReportService) ListReportFiles(ctx context.Context) ([]entity.ReportFile, error) {
+	s.logger.Info("Получение списка файлов отчётов")
+	files, err := s.reports.ListReportFiles(ctx)
+	if err != nil {
+		err = fmt.Errorf("ошибка при получении списка файлов отчётов: %w", err)
+		s.logger.Error(err)
+		return nil, err
+	}
+	if files == nil {
+		files = []entity.ReportFile{}
+	}
+	return files, nil
+}
+
+func (s *ReportService) OpenReportFile(ctx context.Context, id int) (entity.ReportFile, io.ReadCloser, error) {
+	s.logger.Infof("Открытие файла отчёта с ID %d", id)
+	file, err := s.reports.GetReportFile(ctx, id)
+	if err != nil {
+		err = fmt.Errorf("ошибка при получении файла отчёта с ID %d: %w", id, err)
+		s.logger.Error(err)
+		return entity.ReportFile{}, nil, err
+	}
+
+	if file.Status != entity.ReportCompleted || file.Path == nil {
+		err := fmt.Errorf("файл отчёта с ID %d не готов: %w", id, storage.ErrNotFound)
+		s.logger.Warn(err)
+		return entity.ReportFile{}, nil, err
+	}
+
+	r, err := s.files.Open(ctx, *file.Path)
+	if err != nil {
+		err = fmt.Errorf("ошибка при открытии файла отчёта с ID %d: %w", id, err)
+		s.logger.Error(err)
+		return entity.ReportFile{}, nil, err
+	}
+	return file, r, nil
+}
+
+func (s *ReportService) ExportRevenueReport(ctx context.Context, report entity.RevenueReport, force bool, publish func(report entity.RevenueReport, revision int) error) (int, error) {
+	export, err := s.reports.GetReportExport(ctx, report.Year, report.Month)
+	if err != nil {
+		err = fmt.Errorf("ошибка при получении состояния выгрузки за %02d.%d: %w", report.Month, report.Year, err)
+		s.logger.Error(err)
+		return 0, err
+	}
+
+	if export.Status == entity.ReportExportCompleted {
+		if !force && report.LastOperationId <= export.LastOperationId {
+			s.logger.Infof("Выгрузка отчёта за %02d.%d уже завершена, новых операций после %d нет, отправка пропущена", report.Month, report.Year, export.LastOperationId)
+			return 0, nil
+		}
+		if err := s.reports.ResetReportExport(ctx, report.Year, report.Month); err != nil {
+			err = fmt.Errorf("ошибка при сбросе выгрузки за %02d.%d: %w", report.Month, report.Year, err)
+			s.logger.Error(err)
+			return 0, err
+		}
+		s.logger.Infof("Выгрузка отчёта за %02d.%d сброшена для повторной отправки", report.Month, report.Year)
+	}
+
+	if err := publish(report, export.Revision); err != nil {
+		err = fmt.Errorf("ошибка при отправке отчёта за %02d.%d: %w", report.Month, report.Year, err)
+		s.logger.Error(err)
+		return 0, err
+	}
+
+	if err := s.reports.CompleteReportExport(context.WithoutCancel(ctx), report.Year, report.Month, report.LastOperationId); err != nil {
+		err = fmt.Errorf("ошибка при завершении выгрузки за %02d.%d: %w", report.Month, report.Year, err)
+		s.logger.Error(err)
+		return 0, err
+	}
+
+	s.logger.Infof("Отчёт за %02d.%d выгружен, ревизия %d, последняя операция %d", report.Month, report.Year, export.Revision, report.LastOperationId)
+	return 1, nil
+}
+
+func (s *ReportService) failReportFile(ctx context.Context, id int, report entity.RevenueReport, cause error) error {
+	err := fmt.Errorf("ошибка при сохранении файла отчёта за %02d.%d: %w", report.Month, report.Year, cause)
+	s.logger.Error(err)
+	if failErr := s.reports.FailReportFile(ctx, id, cause.Error()); failErr != nil {
+		s.logger.Errorf("Ошибка при пометке файла отчёта с ID %d как неуспешного: %v", id, failErr)
+	}
+	return err
+}
+
+func writeRevenueReportCSV(w io.Writer, report entity.RevenueReport) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"product_id", "product_name", "operations", "revenue"})
+	for _, item := range report.Items {
+		writer.Write([]string{
+			strconv.Itoa(item.ProductId),
+			item.ProductName,
+			strconv.Itoa(item.Operations),
+			strconv.Itoa(item.Revenue),
+		})
+	}
+	writer.Write([]string{"", "total", "", strconv.Itoa(report.Total)})
+	writer.Flush()
+	return writer.Error()
+}
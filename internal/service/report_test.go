@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"user_balance/internal/entity"
+	"user_balance/internal/repository"
+)
+
+type fakeReportRepo struct {
+	repository.Report
+	export entity.ReportExport
+	err    error
+}
+
+func (f *fakeReportRepo) GetReportExport(ctx context.Context, year, month int) (entity.ReportExport, error) {
+	return f.export, f.err
+}
+
+func (f *fakeReportRepo) ResetReportExport(ctx context.Context, year, month int) error {
+	f.export.Status = entity.ReportExportInProgress
+	return nil
+}
+
+func (f *fakeReportRepo) CompleteReportExport(ctx context.Context, year, month, lastOperationID int) error {
+	f.export.Status = entity.ReportExportCompleted
+	f.export.LastOperationId = lastOperationID
+	f.export.Revision++
+	return nil
+}
+
+func TestExportRevenueReport(t *testing.T) {
+	publishErr := errors.New("брокер недоступен")
+
+	tests := []struct {
+		name          string
+		export        entity.ReportExport
+		getErr        error
+		lastOperation int
+		force         bool
+		publishErr    error
+		wantErr       error
+		wantPublished int
+		wantRevisions []int
+		wantExport    entity.ReportExport
+	}{
+		{
+			name:          "первая выгрузка",
+			export:        entity.ReportExport{Status: entity.ReportExportInProgress},
+			lastOperation: 10,
+			wantPublished: 1,
+			wantRevisions: []int{0},
+			wantExport:    entity.ReportExport{Status: entity.ReportExportCompleted, LastOperationId: 10, Revision: 1},
+		},
+		{
+			name:          "повтор завершённой выгрузки без новых операций пропускается",
+			export:        entity.ReportExport{Status: entity.ReportExportCompleted, LastOperationId: 10, Revision: 1},
+			lastOperation: 10,
+			wantExport:    entity.ReportExport{Status: entity.ReportExportCompleted, LastOperationId: 10, Revision: 1},
+		},
+		{
+			name:          "новые операции публикуют следующую ревизию",
+			export:        entity.ReportExport{Status: entity.ReportExportCompleted, LastOperationId: 10, Revision: 1},
+			lastOperation: 15,
+			wantPublished: 1,
+			wantRevisions: []int{1},
+			wantExport:    entity.ReportExport{Status: entity.ReportExportCompleted, LastOperationId: 15, Revision: 2},
+		},
+		{
+			name:          "принудительная выгрузка без новых операций",
+			export:        entity.ReportExport{Status: entity.ReportExportCompleted, LastOperationId: 10, Revision: 1},
+			lastOperation: 10,
+			force:         true,
+			wantPublished: 1,
+			wantRevisions: []int{1},
+			wantExport:    entity.ReportExport{Status: entity.ReportExportCompleted, LastOperationId: 10, Revision: 2},
+		},
+		{
+			name:          "незавершённая выгрузка повторяет ту же ревизию",
+			export:        entity.ReportExport{Status: entity.ReportExportInProgress, LastOperationId: 10, Revision: 1},
+			lastOperation: 12,
+			wantPublished: 1,
+			wantRevisions: []int{1},
+			wantExport:    entity.ReportExport{Status: entity.ReportExportCompleted, LastOperationId: 12, Revision: 2},
+		},
+		{
+			name:          "ошибка публикации оставляет выгрузку незавершённой",
+			export:        entity.ReportExport{Status: entity.ReportExportCompleted, LastOperationId: 10, Revision: 1},
+			lastOperation: 15,
+			publishErr:    publishErr,
+			wantErr:       publishErr,
+			wantRevisions: []int{1},
+			wantExport:    entity.ReportExport{Status: entity.ReportExportInProgress, LastOperationId: 10, Revision: 1},
+		},
+		{
+			name:    "ошибка чтения состояния",
+			getErr:  errors.New("нет соединения"),
+			wantErr: errors.New("нет соединения"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := &fakeReportRepo{export: tt.export, err: tt.getErr}
+			s := NewReportService(nil, reports, nil, testLogger())
+
+			report := entity.RevenueReport{Year: 2024, Month: 5, LastOperationId: tt.lastOperation}
+			var revisions []int
+			published, err := s.ExportRevenueReport(context.Background(), report, tt.force, func(report entity.RevenueReport, revision int) error {
+				revisions = append(revisions, revision)
+				return tt.publishErr
+			})
+
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && err == nil {
+				t.Fatalf("ошибка %v, ожидалась %v", err, tt.wantErr)
+			}
+			if tt.publishErr != nil && !errors.Is(err, tt.publishErr) {
+				t.Errorf("ошибка %v не содержит ошибку публикации", err)
+			}
+			if published != tt.wantPublished {
+				t.Errorf("опубликовано %d, ожидалось %d", published, tt.wantPublished)
+			}
+			if len(revisions) != len(tt.wantRevisions) || len(revisions) > 0 && revisions[0] != tt.wantRevisions[0] {
+				t.Errorf("опубликованы ревизии %v, ожидались %v", revisions, tt.wantRevisions)
+			}
+			if tt.getErr == nil && reports.export != tt.wantExport {
+				t.Errorf("состояние выгрузки %+v, ожидалось %+v", reports.export, tt.wantExport)
+			}
+		})
+	}
+}
+
+func TestExportRevenueReportRetryAfterPublishFailure(t *testing.T) {
+	reports := &fakeReportRepo{export: entity.ReportExport{Status: entity.ReportExportCompleted, LastOperationId: 10, Revision: 1}}
+	s := NewReportService(nil, reports, nil, testLogger())
+	report := entity.RevenueReport{Year: 2024, Month: 5, LastOperationId: 15}
+
+	var revisions []int
+	fail := true
+	publish := func(report entity.RevenueReport, revision int) error {
+		revisions = append(revisions, revision)
+		if fail {
+			fail = false
+			return errors.New("брокер недоступен")
+		}
+		return nil
+	}
+
+	if _, err := s.ExportRevenueReport(context.Background(), report, false, publish); err == nil {
+		t.Fatal("ожидалась ошибка первой публикации")
+	}
+	if _, err := s.ExportRevenueReport(context.Background(), report, false, publish); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ExportRevenueReport(context.Background(), report, false, publish); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(revisions) != 2 || revisions[0] != revisions[1] {
+		t.Errorf("ревизии публикаций %v, ожидался повтор одной ревизии", revisions)
+	}
+	if reports.export.Revision != 2 || reports.export.Status != entity.ReportExportCompleted {
+		t.Errorf("состояние выгрузки %+v", reports.export)
+	}
+}
@@ -2,12 +2,41 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"time"
 	"user_balance/internal/entity"
 	"user_balance/internal/repository"
+	"user_balance/internal/repository/repoerrs"
 
 	"github.com/sirupsen/logrus"
 )
 
+var reservationTransitions = map[entity.ReservationStatus][]entity.ReservationStatus{
+	entity.ReservationPending: {
+		entity.ReservationConfirmed,
+		entity.ReservationRefunded,
+		entity.ReservationExpired,
+	},
+}
+
+func checkReservationTransition(from, to entity.ReservationStatus) error {
+	for _, allowed := range reservationTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+
+	switch from {
+	case entity.ReservationConfirmed:
+		return repoerrs.ErrReservationConfirmed
+	case entity.ReservationRefunded:
+		return repoerrs.ErrReservationRefunded
+	case entity.ReservationExpired:
+		return repoerrs.ErrReservationExpired
+	}
+	return fmt.Errorf("%w: %s -> %s", repoerrs.ErrIllegalStatusTransition, from, to)
+}
+
 type ReservationService struct {
 	repo   repository.Reservation
 	logger *logrus.Logger
@@ -24,30 +53,198 @@ func (s *ReservationService) CreateReservation(ctx context.Context, reservation
 	s.logger.Infof("Создание резервации: %+v", reservation)
 	id, err := s.repo.CreateReservation(ctx, reservation)
 	if err != nil {
-		s.logger.Errorf("Ошибка при создании резервации: %w", err)
+		err = fmt.Errorf("ошибка при создании резервации: %w", err)
+		s.logger.Error(err)
 		return 0, err
 	}
 	s.logger.Infof("Резервация создана с ID: %d", id)
 	return id, nil
 }
 
+func (s *ReservationService) CreateOrderReservation(ctx context.Context, order entity.OrderReservation) ([]int, error) {
+	s.logger.Infof("Создание резервации по заказу: %+v", order)
+	if err := validateOrder(order); err != nil {
+		err = fmt.Errorf("ошибка при создании резервации по заказу с ID %d: %w", order.OrderId, err)
+		s.logger.Error(err)
+		return nil, err
+	}
+	ids, err := s.repo.CreateOrderReservation(ctx, order)
+	if err != nil {
+		err = fmt.Errorf("ошибка при создании резервации по заказу с ID %d: %w", order.OrderId, err)
+		s.logger.Error(err)
+		return nil, err
+	}
+	s.logger.Infof("Резервация по заказу с ID %d создана, ID позиций: %v", order.OrderId, ids)
+	return ids, nil
+}
+
 func (s *ReservationService) GetReservation(ctx context.Context, reservationID int) (entity.Reservation, error) {
 	s.logger.Infof("Получение резервации с ID: %d", reservationID)
 	reservation, err := s.repo.GetReservation(ctx, reservationID)
 	if err != nil {
-		s.logger.Errorf("Ошибка при получении резервации с ID %d: %w", reservationID, err)
+		err = fmt.Errorf("ошибка при получении резервации с ID %d: %w", reservationID, err)
+		s.logger.Error(err)
 		return entity.Reservation{}, err
 	}
 	s.logger.Infof("Резервация с ID %d успешно получена: %+v", reservationID, reservation)
 	return reservation, nil
 }
 
+func (s *ReservationService) GetReservationsByOrder(ctx context.Context, accountID, orderID int) ([]entity.Reservation, error) {
+	s.logger.Infof("Получение резерваций по заказу с ID %d для аккаунта %d", orderID, accountID)
+	reservations, err := s.repo.GetReservationsByOrder(ctx, accountID, orderID)
+	if err != nil {
+		err = fmt.Errorf("ошибка при получении резерваций по заказу с ID %d для аккаунта %d: %w", orderID, accountID, err)
+		s.logger.Error(err)
+		return nil, err
+	}
+	s.logger.Infof("Резервации по заказу с ID %d успешно получены: %d шт.", orderID, len(reservations))
+	return reservations, nil
+}
+
 func (s *ReservationService) RefundReservation(ctx context.Context, reservationId int) error {
 	s.logger.Infof("Возврат резервации с ID: %d", reservationId)
-	if err := s.repo.RefundReservation(ctx, reservationId); err != nil {
-		s.logger.Errorf("Ошибка при возврате резервации с ID %d: %w", reservationId, err)
+	if _, err := s.transition(ctx, reservationId, entity.ReservationRefunded); err != nil {
+		err = fmt.Errorf("ошибка при возврате резервации с ID %d: %w", reservationId, err)
+		s.logger.Error(err)
+		return err
+	}
+	if err := s.repo.RefundReservation(ctx, reservationId, 0); err != nil {
+		err = fmt.Errorf("ошибка при возврате резервации с ID %d: %w", reservationId, err)
+		s.logger.Error(err)
 		return err
 	}
 	s.logger.Infof("Резервация с ID %d успешно возвращена", reservationId)
 	return nil
 }
+
+func (s *ReservationService) PartialRefundReservation(ctx context.Context, reservationId, amount int) error {
+	s.logger.Infof("Частичный возврат резервации с ID %d на сумму %d", reservationId, amount)
+	if amount <= 0 {
+		err := fmt.Errorf("ошибка при частичном возврате резервации с ID %d: %w", reservationId, repoerrs.ErrInvalidAmount)
+		s.logger.Error(err)
+		return err
+	}
+	if _, err := s.transition(ctx, reservationId, entity.ReservationRefunded); err != nil {
+		err = fmt.Errorf("ошибка при частичном возврате резервации с ID %d: %w", reservationId, err)
+		s.logger.Error(err)
+		return err
+	}
+	if err := s.repo.RefundReservation(ctx, reservationId, amount); err != nil {
+		err = fmt.Errorf("ошибка при частичном возврате резервации с ID %d: %w", reservationId, err)
+		s.logger.Error(err)
+		return err
+	}
+	s.logger.Infof("По резервации с ID %d успешно возвращено %d", reservationId, amount)
+	return nil
+}
+
+func (s *ReservationService) ConfirmReservation(ctx context.Context, reservationId int) error {
+	s.logger.Infof("Подтверждение резервации с ID: %d", reservationId)
+	if _, err := s.transition(ctx, reservationId, entity.ReservationConfirmed); err != nil {
+		err = fmt.Errorf("ошибка при подтверждении резервации с ID %d: %w", reservationId, err)
+		s.logger.Error(err)
+		return err
+	}
+	if err := s.repo.CaptureReservation(ctx, reservationId, 0); err != nil {
+		err = fmt.Errorf("ошибка при подтверждении резервации с ID %d: %w", reservationId, err)
+		s.logger.Error(err)
+		return err
+	}
+	s.logger.Infof("Резервация с ID %d успешно подтверждена", reservationId)
+	return nil
+}
+
+func (s *ReservationService) CaptureReservation(ctx context.Context, reservationId, amount int) error {
+	s.logger.Infof("Списание по резервации с ID %d на сумму %d", reservationId, amount)
+	if amount <= 0 {
+		err := fmt.Errorf("ошибка при списании по резервации с ID %d: %w", reservationId, repoerrs.ErrInvalidAmount)
+		s.logger.Error(err)
+		return err
+	}
+	if _, err := s.transition(ctx, reservationId, entity.ReservationConfirmed); err != nil {
+		err = fmt.Errorf("ошибка при списании по резервации с ID %d: %w", reservationId, err)
+		s.logger.Error(err)
+		return err
+	}
+	if err := s.repo.CaptureReservation(ctx, reservationId, amount); err != nil {
+		err = fmt.Errorf("ошибка при списании по резервации с ID %d: %w", reservationId, err)
+		s.logger.Error(err)
+		return err
+	}
+	s.logger.Infof("По резервации с ID %d успешно списано %d", reservationId, amount)
+	return nil
+}
+
+func (s *ReservationService) ConfirmOrder(ctx context.Context, accountId, orderId int) error {
+	s.logger.Infof("Подтверждение заказа с ID %d для аккаунта %d", orderId, accountId)
+	if err := s.repo.ConfirmOrder(ctx, accountId, orderId); err != nil {
+		err = fmt.Errorf("ошибка при подтверждении заказа с ID %d: %w", orderId, err)
+		s.logger.Error(err)
+		return err
+	}
+	s.logger.Infof("Заказ с ID %d успешно подтверждён", orderId)
+	return nil
+}
+
+func (s *ReservationService) RefundOrder(ctx context.Context, accountId, orderId int) error {
+	s.logger.Infof("Возврат заказа с ID %d для аккаунта %d", orderId, accountId)
+	if err := s.repo.RefundOrder(ctx, accountId, orderId); err != nil {
+		err = fmt.Errorf("ошибка при возврате заказа с ID %d: %w", orderId, err)
+		s.logger.Error(err)
+		return err
+	}
+	s.logger.Infof("Заказ с ID %d успешно возвращён", orderId)
+	return nil
+}
+
+func (s *ReservationService) GetStaleReservations(ctx context.Context, ttl time.Duration) ([]int, error) {
+	ids, err := s.repo.GetStaleReservations(ctx, ttl)
+	if err != nil {
+		err = fmt.Errorf("ошибка при получении просроченных резерваций: %w", err)
+		s.logger.Error(err)
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (s *ReservationService) ExpireReservation(ctx context.Context, reservationId int) error {
+	s.logger.Infof("Истечение резервации с ID: %d", reservationId)
+	if _, err := s.transition(ctx, reservationId, entity.ReservationExpired); err != nil {
+		return fmt.Errorf("ошибка при истечении резервации с ID %d: %w", reservationId, err)
+	}
+	if err := s.repo.ExpireReservation(ctx, reservationId); err != nil {
+		return fmt.Errorf("ошибка при истечении резервации с ID %d: %w", reservationId, err)
+	}
+	s.logger.Infof("Резервация с ID %d истекла", reservationId)
+	return nil
+}
+
+func (s *ReservationService) transition(ctx context.Context, reservationId int, to entity.ReservationStatus) (entity.Reservation, error) {
+	reservation, err := s.repo.GetReservation(ctx, reservationId)
+	if err != nil {
+		return entity.Reservation{}, err
+	}
+	if err := checkReservationTransition(reservation.Status, to); err != nil {
+		return entity.Reservation{}, err
+	}
+	return reservation, nil
+}
+
+func validateOrder(order entity.OrderReservation) error {
+	if len(order.Items) == 0 {
+		return repoerrs.ErrInvalidOrder
+	}
+
+	seen := make(map[int]bool, len(order.Items))
+	for _, item := range order.Items {
+		if item.Amount <= 0 {
+			return repoerrs.ErrInvalidAmount
+		}
+		if seen[item.ProductId] {
+			return repoerrs.ErrInvalidOrder
+		}
+		seen[item.ProductId] = true
+	}
+	return nil
+}
@@ -2,8 +2,11 @@ package service
 
 import (
 	"context"
+	"io"
+	"time"
 	"user_balance/internal/entity"
 	"user_balance/internal/repository"
+	"user_balance/internal/storage"
 
 	"github.com/sirupsen/logrus"
 )
@@ -18,8 +21,17 @@ type Account interface {
 
 type Reservation interface {
 	CreateReservation(ctx context.Context, reservation entity.Reservation) (int, error)
+	CreateOrderReservation(ctx context.Context, order entity.OrderReservation) ([]int, error)
 	GetReservation(ctx context.Context, reservationID int) (entity.Reservation, error)
+	GetReservationsByOrder(ctx context.Context, accountID, orderID int) ([]entity.Reservation, error)
 	RefundReservation(ctx context.Context, reservationId int) error
+	PartialRefundReservation(ctx context.Context, reservationId, amount int) error
+	ConfirmReservation(ctx context.Context, reservationId int) error
+	CaptureReservation(ctx context.Context, reservationId, amount int) error
+	ConfirmOrder(ctx context.Context, accountId, orderId int) error
+	RefundOrder(ctx context.Context, accountId, orderId int) error
+	GetStaleReservations(ctx context.Context, ttl time.Duration) ([]int, error)
+	ExpireReservation(ctx context.Context, reservationId int) error
 }
 
 type Product interface {
@@ -27,16 +39,55 @@ type Product interface {
 	GetProduct(ctx context.Context, id int) (entity.Product, error)
 }
 
+type Operation interface {
+	GetAccountOperations(ctx context.Context, filter entity.OperationFilter, cursor string) (entity.OperationPage, error)
+}
+
+type Statement interface {
+	GenerateStatement(ctx context.Context, accountID int, from, to time.Time, emit func(entity.StatementEntry) error) error
+}
+
+type Report interface {
+	GetRevenueReport(ctx context.Context, year int, month time.Month) (entity.RevenueReport, error)
+	SaveRevenueReport(ctx context.Context, report entity.RevenueReport) (entity.ReportFile, error)
+	ListReportFiles(ctx context.Context) ([]entity.ReportFile, error)
+	OpenReportFile(ctx context.Context, id int) (entity.ReportFile, io.ReadCloser, error)
+	ExportRevenueReport(ctx context.Context, report entity.RevenueReport, force bool, publish func(report entity.RevenueReport, revision int) error) (int, error)
+}
+
+type Jobs interface {
+	ListJobs(ctx context.Context) ([]entity.JobInfo, error)
+	ListJobRuns(ctx context.Context, name string, limit int) ([]entity.JobRun, error)
+	TriggerJob(ctx context.Context, name string) (entity.JobRun, error)
+	BackfillMonthlyReport(ctx context.Context, year int, month time.Month, force bool) (entity.JobRun, error)
+}
+
+type Idempotency interface {
+	Begin(ctx context.Context, key, requestHash string) (*entity.IdempotencyRecord, error)
+	Complete(ctx context.Context, key string, statusCode int, contentType string, response []byte) error
+	Release(ctx context.Context, key string) error
+	Cleanup(ctx context.Context) (int, error)
+}
+
 type Service struct {
 	Account     Account
 	Reservation Reservation
 	Product     Product
+	Operation   Operation
+	Statement   Statement
+	Report      Report
+	Jobs        Jobs
+	Idempotency Idempotency
 }
 
-func NewService(repository *repository.Repository, logger *logrus.Logger) *Service {
+func NewService(repository *repository.Repository, files storage.Storage, logger *logrus.Logger) *Service {
 	return &Service{
 		Account:     NewAccountService(repository, logger),
 		Reservation: NewReservationService(repository, logger),
 		Product:     NewProductService(repository, logger),
+		Operation:   NewOperationService(repository, repository, logger),
+		Statement:   NewStatementService(repository, repository, logger),
+		Report:      NewReportService(repository, repository, files, logger),
+		Idempotency: NewIdempotencyService(repository, logger),
 	}
 }
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"user_balance/internal/entity"
+	"user_balance/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+type StatementService struct {
+	repo     repository.Operation
+	accounts repository.Account
+	logger   *logrus.Logger
+}
+
+func NewStatementService(repo repository.Operation, accounts repository.Account, logger *logrus.Logger) *StatementService {
+	return &StatementService{
+		repo:     repo,
+		accounts: accounts,
+		logger:   logger,
+	}
+}
+
+func (s *StatementService) GenerateStatement(ctx context.Context, accountID int, from, to time.Time, emit func(entity.StatementEntry) error) error {
+	s.logger.Infof("Формирование выписки по аккаунту с ID %d за период %s - %s", accountID, from, to)
+
+	if _, err := s.accounts.GetAccount(ctx, accountID); err != nil {
+		err = fmt.Errorf("ошибка при получении аккаунта с ID %d: %w", accountID, err)
+		s.logger.Error(err)
+		return err
+	}
+
+	balance, err := s.repo.GetBalanceBefore(ctx, accountID, from)
+	if err != nil {
+		err = fmt.Errorf("ошибка при расчёте входящего остатка аккаунта с ID %d: %w", accountID, err)
+		s.logger.Error(err)
+		return err
+	}
+
+	err = emit(entity.StatementEntry{
+		Type:    entity.StatementOpeningBalance,
+		Balance: balance,
+		Date:    from,
+	})
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	err = s.repo.StreamAccountOperations(ctx, accountID, from, to, func(op entity.Operation) error {
+		balance += op.BalanceEffect()
+		count++
+		return emit(entity.StatementEntry{
+			Type:          entity.StatementOperation,
+			OperationId:   op.Id,
+			OperationType: op.OperationType,
+			ProductId:     op.ProductId,
+			Amount:        op.Amount,
+			Balance:       balance,
+			Date:          op.CreatedAt,
+		})
+	})
+	if err != nil {
+		err = fmt.Errorf("ошибка при формировании выписки по аккаунту с ID %d: %w", accountID, err)
+		s.logger.Error(err)
+		return err
+	}
+
+	err = emit(entity.StatementEntry{
+		Type:    entity.StatementClosingBalance,
+		Balance: balance,
+		Date:    to,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infof("Выписка по аккаунту с ID %d сформирована: %d операций", accountID, count)
+	return nil
+}
@@ -0,0 +1,4 @@
+drop table if exists operations;
+drop table if exists reservations;
+drop table if exists products;
+drop table if exists accounts;
@@ -0,0 +1,2 @@
+alter table reservations
+    drop column if exists confirmed_at;
@@ -0,0 +1,2 @@
+alter table reservations
+    add column if not exists confirmed_at timestamp default null;
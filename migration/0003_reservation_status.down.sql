@@ -0,0 +1,5 @@
+alter table reservations
+    drop constraint if exists reservations_status_check;
+
+alter table reservations
+    drop column if exists status;
@@ -0,0 +1,19 @@
+alter table reservations
+    add column if not exists status varchar(32) not null default 'pending';
+
+update reservations
+set status = 'confirmed'
+where status = 'pending' and confirmed_at is not null;
+
+update reservations
+set status = 'refunded', deleted_at = null
+where status = 'pending' and deleted_at is not null;
+
+do $$
+begin
+    if not exists (select 1 from pg_constraint where conname = 'reservations_status_check') then
+        alter table reservations
+            add constraint reservations_status_check
+            check (status in ('pending', 'confirmed', 'refunded', 'expired'));
+    end if;
+end $$;
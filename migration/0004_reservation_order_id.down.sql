@@ -0,0 +1,5 @@
+drop index if exists reservations_order_id_index;
+drop index if exists reservations_account_product_order_uindex;
+
+alter table reservations
+    drop column if exists order_id;
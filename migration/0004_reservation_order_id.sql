@@ -0,0 +1,8 @@
+alter table reservations
+    add column if not exists order_id int default null;
+
+create unique index if not exists reservations_account_product_order_uindex
+    on reservations (account_id, product_id, order_id);
+
+create index if not exists reservations_order_id_index
+    on reservations (order_id);
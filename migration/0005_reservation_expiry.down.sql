@@ -0,0 +1 @@
+drop index if exists reservations_status_created_at_index;
@@ -0,0 +1,2 @@
+create index if not exists reservations_status_created_at_index
+    on reservations (status, created_at);
@@ -0,0 +1,17 @@
+do $$
+begin
+    if not exists (
+        select 1 from information_schema.columns
+        where table_name = 'reservations' and column_name = 'remaining'
+    ) then
+        alter table reservations add column remaining int;
+
+        update reservations
+        set remaining = case when status = 'pending' then amount else 0 end;
+
+        alter table reservations alter column remaining set not null;
+        alter table reservations
+            add constraint reservations_remaining_check
+            check (remaining >= 0 and remaining <= amount);
+    end if;
+end $$;
@@ -0,0 +1,5 @@
+alter table accounts
+    drop constraint if exists accounts_held_check;
+
+alter table accounts
+    drop column if exists held;
@@ -0,0 +1,21 @@
+do $$
+begin
+    if not exists (
+        select 1 from information_schema.columns
+        where table_name = 'accounts' and column_name = 'held'
+    ) then
+        alter table accounts add column held int not null default 0;
+
+        update accounts a
+        set held = r.total
+        from (
+            select account_id, sum(remaining) as total
+            from reservations
+            where status = 'pending' and deleted_at is null
+            group by account_id
+        ) r
+        where a.id = r.account_id;
+
+        alter table accounts add constraint accounts_held_check check (held >= 0);
+    end if;
+end $$;
@@ -0,0 +1,8 @@
+create table if not exists idempotency_keys (
+    key          varchar(255) primary key,
+    request_hash varchar(64) not null,
+    status_code  int       default null,
+    response     bytea     default null,
+    created_at   timestamp not null default now(),
+    completed_at timestamp default null
+);
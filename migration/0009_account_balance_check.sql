@@ -0,0 +1,7 @@
+do $$
+begin
+    if not exists (select 1 from pg_constraint where conname = 'accounts_balance_check') then
+        alter table accounts
+            add constraint accounts_balance_check check (balance >= 0) not valid;
+    end if;
+end $$;
@@ -0,0 +1,5 @@
+drop trigger if exists ledger_postings_balanced on ledger_postings;
+drop function if exists ledger_check_balanced();
+drop table if exists ledger_postings;
+drop table if exists ledger_transactions;
+drop table if exists ledger_accounts;
@@ -0,0 +1,85 @@
+-- Двойная запись: сумма проводок каждой транзакции равна нулю.
+-- Положительная сумма проводки увеличивает остаток счёта, отрицательная — уменьшает.
+create table if not exists ledger_accounts (
+    id         serial primary key,
+    code       varchar(255) not null unique,
+    account_id int          default null unique,
+    created_at timestamp not null default now(),
+    foreign key (account_id) references accounts (id)
+);
+
+insert into ledger_accounts (code)
+values ('external_cash'), ('revenue'), ('reservation_holds')
+on conflict (code) do nothing;
+
+create table if not exists ledger_transactions (
+    id             serial primary key,
+    type           varchar(255) not null,
+    reservation_id int          default null,
+    created_at     timestamp not null default now(),
+    foreign key (reservation_id) references reservations (id)
+);
+
+create table if not exists ledger_postings (
+    id                serial primary key,
+    transaction_id    int       not null,
+    ledger_account_id int       not null,
+    amount            int       not null,
+    created_at        timestamp not null default now(),
+    foreign key (transaction_id) references ledger_transactions (id),
+    foreign key (ledger_account_id) references ledger_accounts (id)
+);
+
+create index if not exists ledger_postings_transaction_id_index
+    on ledger_postings (transaction_id);
+
+create index if not exists ledger_postings_ledger_account_id_index
+    on ledger_postings (ledger_account_id);
+
+create or replace function ledger_check_balanced() returns trigger as $$
+begin
+    if (select coalesce(sum(amount), 0) from ledger_postings where transaction_id = new.transaction_id) <> 0 then
+        raise exception 'ledger transaction % is unbalanced', new.transaction_id;
+    end if;
+    return null;
+end
+$$ language plpgsql;
+
+do $$
+begin
+    if not exists (select 1 from pg_trigger where tgname = 'ledger_postings_balanced') then
+        create constraint trigger ledger_postings_balanced
+            after insert on ledger_postings
+            deferrable initially deferred
+            for each row execute function ledger_check_balanced();
+    end if;
+end $$;
+
+do $$
+declare
+    opening_id int;
+begin
+    if not exists (select 1 from ledger_transactions where type = 'opening_balance') then
+        insert into ledger_accounts (code, account_id)
+        select 'account:' || id, id from accounts
+        on conflict do nothing;
+
+        insert into ledger_transactions (type) values ('opening_balance') returning id into opening_id;
+
+        insert into ledger_postings (transaction_id, ledger_account_id, amount)
+        select opening_id, la.id, a.balance
+        from accounts a
+        join ledger_accounts la on la.account_id = a.id
+        where a.balance <> 0;
+
+        insert into ledger_postings (transaction_id, ledger_account_id, amount)
+        select opening_id, (select id from ledger_accounts where code = 'reservation_holds'), sum(held)
+        from accounts
+        having coalesce(sum(held), 0) <> 0;
+
+        insert into ledger_postings (transaction_id, ledger_account_id, amount)
+        select opening_id, (select id from ledger_accounts where code = 'external_cash'), -sum(balance + held)
+        from accounts
+        having coalesce(sum(balance + held), 0) <> 0;
+    end if;
+end $$;
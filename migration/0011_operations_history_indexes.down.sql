@@ -0,0 +1,3 @@
+drop index if exists operations_account_type_index;
+drop index if exists operations_account_amount_index;
+drop index if exists operations_account_created_at_index;
@@ -0,0 +1,8 @@
+create index if not exists operations_account_created_at_index
+    on operations (account_id, created_at, id);
+
+create index if not exists operations_account_amount_index
+    on operations (account_id, amount, id);
+
+create index if not exists operations_account_type_index
+    on operations (account_id, operation_type);
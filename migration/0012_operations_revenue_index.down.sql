@@ -0,0 +1 @@
+drop index if exists operations_type_created_at_index;
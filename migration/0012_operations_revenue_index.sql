@@ -0,0 +1,2 @@
+create index if not exists operations_type_created_at_index
+    on operations (operation_type, created_at);
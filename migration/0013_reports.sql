@@ -0,0 +1,12 @@
+create table if not exists reports (
+    id         serial primary key,
+    year       int          not null,
+    month      int          not null,
+    status     varchar(32)  not null default 'pending',
+    checksum   varchar(64)           default null,
+    path       varchar(1024)         default null,
+    error      text                  default null,
+    created_at timestamp not null default now(),
+    updated_at timestamp     default null,
+    unique (year, month)
+);
@@ -0,0 +1,15 @@
+create table if not exists outbox (
+    id              bigserial primary key,
+    event_type      varchar(255) not null,
+    account_id      int          not null,
+    payload         jsonb        not null,
+    attempts        int          not null default 0,
+    last_error      text                  default null,
+    next_attempt_at timestamp    not null default now(),
+    created_at      timestamp    not null default now(),
+    sent_at         timestamp             default null
+);
+
+create index if not exists outbox_pending_index
+    on outbox (next_attempt_at, id)
+    where sent_at is null;
@@ -0,0 +1,16 @@
+create table if not exists job_runs (
+    id             bigserial primary key,
+    job_name       varchar(255) not null,
+    trigger        varchar(32)  not null default 'schedule',
+    scheduled_at   timestamp    not null,
+    instance       varchar(255) not null,
+    status         varchar(32)  not null default 'running',
+    rows_processed int          not null default 0,
+    error          text                  default null,
+    started_at     timestamp    not null default now(),
+    finished_at    timestamp             default null,
+    unique (job_name, scheduled_at)
+);
+
+create index if not exists job_runs_job_name_started_at_index
+    on job_runs (job_name, started_at desc);
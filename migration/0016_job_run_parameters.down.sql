@@ -0,0 +1,2 @@
+alter table job_runs
+    drop column if exists parameters;
@@ -0,0 +1,2 @@
+alter table job_runs
+    add column if not exists parameters jsonb default null;
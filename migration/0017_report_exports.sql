@@ -0,0 +1,11 @@
+create table if not exists report_exports (
+    year              int         not null,
+    month             int         not null,
+    status            varchar(32) not null default 'in_progress',
+    last_operation_id int         not null default 0,
+    published         int         not null default 0,
+    created_at        timestamp   not null default now(),
+    updated_at        timestamp            default null,
+    completed_at      timestamp            default null,
+    primary key (year, month)
+);
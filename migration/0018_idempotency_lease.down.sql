@@ -0,0 +1,3 @@
+alter table idempotency_keys
+    drop column if exists content_type,
+    drop column if exists locked_until;
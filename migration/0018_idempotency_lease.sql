@@ -0,0 +1,3 @@
+alter table idempotency_keys
+    add column if not exists locked_until timestamp    default null,
+    add column if not exists content_type varchar(255) not null default '';
@@ -0,0 +1,4 @@
+drop index if exists outbox_account_pending_index;
+
+alter table outbox
+    drop column if exists locked_until;
@@ -0,0 +1,6 @@
+alter table outbox
+    add column if not exists locked_until timestamp default null;
+
+create index if not exists outbox_account_pending_index
+    on outbox (account_id, id)
+    where sent_at is null;
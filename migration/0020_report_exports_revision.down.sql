@@ -0,0 +1,2 @@
+alter table report_exports
+    rename column revision to published;
@@ -0,0 +1,2 @@
+alter table report_exports
+    rename column published to revision;
@@ -0,0 +1,4 @@
+drop index if exists idempotency_keys_expires_at_index;
+
+alter table idempotency_keys
+    drop column if exists expires_at;
@@ -0,0 +1,5 @@
+alter table idempotency_keys
+    add column if not exists expires_at timestamp not null default now() + interval '24 hours';
+
+create index if not exists idempotency_keys_expires_at_index
+    on idempotency_keys (expires_at);
@@ -0,0 +1,6 @@
+package migration
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
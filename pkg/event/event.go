@@ -0,0 +1,78 @@
+package event
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	SpecVersion     = "1.0"
+	Source          = "user-balance"
+	DataContentType = "application/json"
+)
+
+type Envelope struct {
+	SpecVersion     string          `json:"specversion"`
+	Id              string          `json:"id"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	SchemaVersion   int             `json:"schemaversion"`
+	Time            time.Time       `json:"time"`
+	Subject         string          `json:"subject,omitempty"`
+	PartitionKey    string          `json:"partitionkey"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+func New(eventType, partitionKey string, occurredAt time.Time, data interface{}) (Envelope, error) {
+	id, err := newId()
+	if err != nil {
+		return Envelope{}, err
+	}
+	return NewWithId(id, eventType, partitionKey, occurredAt, data)
+}
+
+func NewWithId(id, eventType, partitionKey string, occurredAt time.Time, data interface{}) (Envelope, error) {
+	schemaVersion, ok := SchemaVersions[eventType]
+	if !ok {
+		return Envelope{}, fmt.Errorf("неизвестный тип события: %s", eventType)
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	return Envelope{
+		SpecVersion:     SpecVersion,
+		Id:              id,
+		Type:            eventType,
+		Source:          Source,
+		SchemaVersion:   schemaVersion,
+		Time:            occurredAt.UTC(),
+		Subject:         partitionKey,
+		PartitionKey:    partitionKey,
+		DataContentType: DataContentType,
+		Data:            payload,
+	}, nil
+}
+
+func (e Envelope) Decode(v interface{}) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+func AccountKey(accountID int) string {
+	return fmt.Sprintf("account-%d", accountID)
+}
+
+func newId() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,133 @@
+package event
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"user_balance/pkg/event/eventpb"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const ProtobufContentType = "application/protobuf"
+
+func MarshalProtobuf(e Envelope) ([]byte, error) {
+	message := &eventpb.Envelope{
+		SpecVersion:     e.SpecVersion,
+		Id:              e.Id,
+		Type:            e.Type,
+		Source:          e.Source,
+		SchemaVersion:   int32(e.SchemaVersion),
+		Time:            timestamp(e.Time),
+		Subject:         e.Subject,
+		PartitionKey:    e.PartitionKey,
+		DataContentType: ProtobufContentType,
+	}
+
+	switch {
+	case strings.HasPrefix(e.Type, "user_balance.operation."):
+		var data OperationRecorded
+		if err := e.Decode(&data); err != nil {
+			return nil, err
+		}
+		message.Data = &eventpb.Envelope_Operation{Operation: operationRecordedProto(data)}
+	case strings.HasPrefix(e.Type, "user_balance.reservation."):
+		var data ReservationChanged
+		if err := e.Decode(&data); err != nil {
+			return nil, err
+		}
+		message.Data = &eventpb.Envelope_Reservation{Reservation: reservationChangedProto(data)}
+	case e.Type == TypeRevenueReport:
+		var data RevenueReportGenerated
+		if err := e.Decode(&data); err != nil {
+			return nil, err
+		}
+		message.Data = &eventpb.Envelope_RevenueReport{RevenueReport: revenueReportGeneratedProto(data)}
+	case e.Type == TypeCommandResult:
+		var data CommandProcessed
+		if err := e.Decode(&data); err != nil {
+			return nil, err
+		}
+		message.Data = &eventpb.Envelope_CommandResult{CommandResult: commandProcessedProto(data)}
+	default:
+		return nil, fmt.Errorf("нет Protobuf схемы для события %s", e.Type)
+	}
+
+	return proto.Marshal(message)
+}
+
+func operationRecordedProto(data OperationRecorded) *eventpb.OperationRecorded {
+	return &eventpb.OperationRecorded{
+		OperationId:   int64(data.OperationId),
+		AccountId:     int64(data.AccountId),
+		OperationType: data.OperationType,
+		Amount:        int64(data.Amount),
+		BalanceEffect: int64(data.BalanceEffect),
+		ProductId:     optionalInt(data.ProductId),
+		CreatedAt:     timestamp(data.CreatedAt),
+	}
+}
+
+func reservationChangedProto(data ReservationChanged) *eventpb.ReservationChanged {
+	return &eventpb.ReservationChanged{
+		ReservationId: int64(data.ReservationId),
+		AccountId:     int64(data.AccountId),
+		ProductId:     int64(data.ProductId),
+		OrderId:       optionalInt(data.OrderId),
+		Amount:        int64(data.Amount),
+		Remaining:     int64(data.Remaining),
+		Status:        data.Status,
+		OccurredAt:    timestamp(data.OccurredAt),
+	}
+}
+
+func revenueReportGeneratedProto(data RevenueReportGenerated) *eventpb.RevenueReportGenerated {
+	items := make([]*eventpb.RevenueReportItem, 0, len(data.Items))
+	for _, item := range data.Items {
+		items = append(items, &eventpb.RevenueReportItem{
+			ProductId:   int64(item.ProductId),
+			ProductName: item.ProductName,
+			Revenue:     int64(item.Revenue),
+			Operations:  int64(item.Operations),
+		})
+	}
+	return &eventpb.RevenueReportGenerated{
+		Year:        int32(data.Year),
+		Month:       int32(data.Month),
+		Total:       int64(data.Total),
+		Items:       items,
+		GeneratedAt: timestamp(data.GeneratedAt),
+	}
+}
+
+func commandProcessedProto(data CommandProcessed) *eventpb.CommandProcessed {
+	return &eventpb.CommandProcessed{
+		Key:           data.Key,
+		Type:          data.Type,
+		Success:       data.Success,
+		AccountId:     int64(data.AccountId),
+		ToAccountId:   int64(data.ToAccountId),
+		ReservationId: int64(data.ReservationId),
+		Balance:       optionalInt(data.Balance),
+		ToBalance:     optionalInt(data.ToBalance),
+		ErrorCode:     data.ErrorCode,
+		Error:         data.Error,
+	}
+}
+
+func optionalInt(value *int) *int64 {
+	if value == nil {
+		return nil
+	}
+	v := int64(*value)
+	return &v
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,240 @@
+package event
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"user_balance/pkg/event/eventpb"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestGeneratedCodeMatchesSchema(t *testing.T) {
+	generated := map[string]protoreflect.FileDescriptor{
+		"envelope.proto":    eventpb.File_envelope_proto,
+		"operation.proto":   eventpb.File_operation_proto,
+		"report.proto":      eventpb.File_report_proto,
+		"reservation.proto": eventpb.File_reservation_proto,
+		"command.proto":     eventpb.File_command_proto,
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: []string{"proto"}}),
+	}
+	for name, fd := range generated {
+		files, err := compiler.Compile(context.Background(), name)
+		if err != nil {
+			t.Fatalf("не удалось скомпилировать схему %s: %v", name, err)
+		}
+
+		want := protodesc.ToFileDescriptorProto(files[0])
+		got := protodesc.ToFileDescriptorProto(fd)
+		want.SourceCodeInfo = nil
+		got.SourceCodeInfo = nil
+		if !proto.Equal(want, got) {
+			t.Errorf("код eventpb для %s не соответствует схеме, выполните make proto", name)
+		}
+	}
+}
+
+func decodeEnvelope(t *testing.T, e Envelope) *eventpb.Envelope {
+	t.Helper()
+
+	b, err := MarshalProtobuf(e)
+	if err != nil {
+		t.Fatalf("не удалось сериализовать событие %s: %v", e.Type, err)
+	}
+
+	var msg eventpb.Envelope
+	if err := proto.Unmarshal(b, &msg); err != nil {
+		t.Fatalf("байты события %s не разбираются по схеме: %v", e.Type, err)
+	}
+
+	assertEqual(t, "spec_version", msg.GetSpecVersion(), e.SpecVersion)
+	assertEqual(t, "id", msg.GetId(), e.Id)
+	assertEqual(t, "type", msg.GetType(), e.Type)
+	assertEqual(t, "source", msg.GetSource(), e.Source)
+	assertEqual(t, "schema_version", msg.GetSchemaVersion(), int32(e.SchemaVersion))
+	assertEqual(t, "partition_key", msg.GetPartitionKey(), e.PartitionKey)
+	assertEqual(t, "data_content_type", msg.GetDataContentType(), ProtobufContentType)
+	assertTimestamp(t, "time", msg.GetTime(), e.Time)
+	return &msg
+}
+
+func assertEqual[T comparable](t *testing.T, name string, got, want T) {
+	t.Helper()
+
+	if got != want {
+		t.Errorf("%s = %v, ожидалось %v", name, got, want)
+	}
+}
+
+func assertTimestamp(t *testing.T, name string, ts *timestamppb.Timestamp, want time.Time) {
+	t.Helper()
+
+	if ts == nil {
+		t.Errorf("%s отсутствует, ожидалось %s", name, want)
+		return
+	}
+	if got := ts.AsTime(); !got.Equal(want) {
+		t.Errorf("%s = %s, ожидалось %s", name, got, want)
+	}
+}
+
+func TestMarshalProtobufOperationRecorded(t *testing.T) {
+	productID := 7
+	createdAt := time.Date(2024, 5, 14, 10, 30, 0, 123000000, time.UTC)
+	e, err := New(TypeOperationWithdraw, AccountKey(42), createdAt, OperationRecorded{
+		OperationId:   1001,
+		AccountId:     42,
+		OperationType: "withdraw",
+		Amount:        250,
+		BalanceEffect: -250,
+		ProductId:     &productID,
+		CreatedAt:     createdAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := decodeEnvelope(t, e).GetOperation()
+	if data == nil {
+		t.Fatal("data не содержит operation")
+	}
+	assertEqual(t, "operation_id", data.GetOperationId(), int64(1001))
+	assertEqual(t, "account_id", data.GetAccountId(), int64(42))
+	assertEqual(t, "operation_type", data.GetOperationType(), "withdraw")
+	assertEqual(t, "amount", data.GetAmount(), int64(250))
+	assertEqual(t, "balance_effect", data.GetBalanceEffect(), int64(-250))
+	assertEqual(t, "product_id", data.GetProductId(), int64(7))
+	assertTimestamp(t, "created_at", data.GetCreatedAt(), createdAt)
+}
+
+func TestMarshalProtobufReservationChanged(t *testing.T) {
+	orderID := 555
+	occurredAt := time.Date(2024, 5, 14, 11, 0, 0, 0, time.UTC)
+	e, err := New(TypeReservationRefunded, AccountKey(42), occurredAt, ReservationChanged{
+		ReservationId: 17,
+		AccountId:     42,
+		ProductId:     7,
+		OrderId:       &orderID,
+		Amount:        40,
+		Remaining:     60,
+		Status:        "pending",
+		OccurredAt:    occurredAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := decodeEnvelope(t, e).GetReservation()
+	if data == nil {
+		t.Fatal("data не содержит reservation")
+	}
+	assertEqual(t, "reservation_id", data.GetReservationId(), int64(17))
+	assertEqual(t, "account_id", data.GetAccountId(), int64(42))
+	assertEqual(t, "product_id", data.GetProductId(), int64(7))
+	assertEqual(t, "order_id", data.GetOrderId(), int64(555))
+	assertEqual(t, "amount", data.GetAmount(), int64(40))
+	assertEqual(t, "remaining", data.GetRemaining(), int64(60))
+	assertEqual(t, "status", data.GetStatus(), "pending")
+	assertTimestamp(t, "occurred_at", data.GetOccurredAt(), occurredAt)
+}
+
+func TestMarshalProtobufReservationWithoutOrder(t *testing.T) {
+	e, err := New(TypeReservationCreated, AccountKey(42), time.Now(), ReservationChanged{
+		ReservationId: 18,
+		AccountId:     42,
+		ProductId:     7,
+		Amount:        100,
+		Remaining:     100,
+		Status:        "pending",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := decodeEnvelope(t, e).GetReservation()
+	if data == nil {
+		t.Fatal("data не содержит reservation")
+	}
+	if data.OrderId != nil {
+		t.Errorf("order_id = %d, ожидалось отсутствие поля", data.GetOrderId())
+	}
+}
+
+func TestMarshalProtobufRevenueReportGenerated(t *testing.T) {
+	generatedAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	e, err := NewWithId("report-2024-05", TypeRevenueReport, "report-2024-05", generatedAt, RevenueReportGenerated{
+		Year:  2024,
+		Month: 5,
+		Total: 1500,
+		Items: []RevenueReportItem{
+			{ProductId: 1, ProductName: "cleaning", Revenue: 1000, Operations: 3},
+			{ProductId: 2, ProductName: "delivery", Revenue: 500, Operations: 1},
+		},
+		GeneratedAt: generatedAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := decodeEnvelope(t, e).GetRevenueReport()
+	if data == nil {
+		t.Fatal("data не содержит revenue_report")
+	}
+	assertEqual(t, "year", data.GetYear(), int32(2024))
+	assertEqual(t, "month", data.GetMonth(), int32(5))
+	assertEqual(t, "total", data.GetTotal(), int64(1500))
+	assertTimestamp(t, "generated_at", data.GetGeneratedAt(), generatedAt)
+
+	items := data.GetItems()
+	if len(items) != 2 {
+		t.Fatalf("items содержит %d элементов, ожидалось 2", len(items))
+	}
+	assertEqual(t, "items[1].product_id", items[1].GetProductId(), int64(2))
+	assertEqual(t, "items[1].product_name", items[1].GetProductName(), "delivery")
+	assertEqual(t, "items[1].revenue", items[1].GetRevenue(), int64(500))
+	assertEqual(t, "items[1].operations", items[1].GetOperations(), int64(1))
+}
+
+func TestMarshalProtobufCommandProcessed(t *testing.T) {
+	balance := 0
+	e, err := New(TypeCommandResult, "cmd-1", time.Now(), CommandProcessed{
+		Key:       "cmd-1",
+		Type:      "withdraw",
+		Success:   true,
+		AccountId: 42,
+		Balance:   &balance,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := decodeEnvelope(t, e).GetCommandResult()
+	if data == nil {
+		t.Fatal("data не содержит command_result")
+	}
+	assertEqual(t, "key", data.GetKey(), "cmd-1")
+	assertEqual(t, "type", data.GetType(), "withdraw")
+	assertEqual(t, "success", data.GetSuccess(), true)
+	assertEqual(t, "account_id", data.GetAccountId(), int64(42))
+	if data.Balance == nil {
+		t.Error("balance отсутствует, ожидалось 0")
+	}
+	if data.ToBalance != nil {
+		t.Errorf("to_balance = %d, ожидалось отсутствие поля", data.GetToBalance())
+	}
+}
+
+func TestMarshalProtobufUnknownType(t *testing.T) {
+	e := Envelope{Type: "user_balance.unknown"}
+	if _, err := MarshalProtobuf(e); err == nil {
+		t.Error("ожидалась ошибка для события без Protobuf схемы")
+	}
+}
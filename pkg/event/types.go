@@ -0,0 +1,93 @@
+package event
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	TypeOperationDeposit     = "user_balance.operation.deposit"
+	TypeOperationWithdraw    = "user_balance.operation.withdraw"
+	TypeOperationTransferIn  = "user_balance.operation.transfer_in"
+	TypeOperationTransferOut = "user_balance.operation.transfer_out"
+	TypeOperationReservation = "user_balance.operation.reservation"
+	TypeOperationRefund      = "user_balance.operation.refund"
+	TypeOperationExpired     = "user_balance.operation.expired"
+	TypeOperationRevenue     = "user_balance.operation.revenue"
+	TypeReservationCreated   = "user_balance.reservation.created"
+	TypeReservationConfirmed = "user_balance.reservation.confirmed"
+	TypeReservationRefunded  = "user_balance.reservation.refunded"
+	TypeReservationExpired   = "user_balance.reservation.expired"
+	TypeRevenueReport        = "user_balance.report.revenue"
+	TypeCommandResult        = "user_balance.command.result"
+)
+
+var SchemaVersions = map[string]int{
+	TypeOperationDeposit:     1,
+	TypeOperationWithdraw:    1,
+	TypeOperationTransferIn:  1,
+	TypeOperationTransferOut: 1,
+	TypeOperationReservation: 1,
+	TypeOperationRefund:      1,
+	TypeOperationExpired:     1,
+	TypeOperationRevenue:     1,
+	TypeReservationCreated:   1,
+	TypeReservationConfirmed: 1,
+	TypeReservationRefunded:  1,
+	TypeReservationExpired:   1,
+	TypeRevenueReport:        1,
+	TypeCommandResult:        1,
+}
+
+func OperationType(operationType string) string {
+	return fmt.Sprintf("user_balance.operation.%s", operationType)
+}
+
+type OperationRecorded struct {
+	OperationId   int       `json:"operation_id"`
+	AccountId     int       `json:"account_id"`
+	OperationType string    `json:"operation_type"`
+	Amount        int       `json:"amount"`
+	BalanceEffect int       `json:"balance_effect"`
+	ProductId     *int      `json:"product_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ReservationChanged struct {
+	ReservationId int       `json:"reservation_id"`
+	AccountId     int       `json:"account_id"`
+	ProductId     int       `json:"product_id"`
+	OrderId       *int      `json:"order_id,omitempty"`
+	Amount        int       `json:"amount"`
+	Remaining     int       `json:"remaining"`
+	Status        string    `json:"status"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+type RevenueReportItem struct {
+	ProductId   int    `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Revenue     int    `json:"revenue"`
+	Operations  int    `json:"operations"`
+}
+
+type RevenueReportGenerated struct {
+	Year        int                 `json:"year"`
+	Month       int                 `json:"month"`
+	Total       int                 `json:"total"`
+	Items       []RevenueReportItem `json:"items"`
+	GeneratedAt time.Time           `json:"generated_at"`
+}
+
+type CommandProcessed struct {
+	Key           string `json:"key,omitempty"`
+	Type          string `json:"type"`
+	Success       bool   `json:"success"`
+	AccountId     int    `json:"account_id,omitempty"`
+	ToAccountId   int    `json:"to_account_id,omitempty"`
+	ReservationId int    `json:"reservation_id,omitempty"`
+	Balance       *int   `json:"balance,omitempty"`
+	ToBalance     *int   `json:"to_balance,omitempty"`
+	ErrorCode     string `json:"error_code,omitempty"`
+	Error         string `json:"error,omitempty"`
+}